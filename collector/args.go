@@ -0,0 +1,220 @@
+package collector
+
+import (
+	"errors"
+	"strings"
+)
+
+// TestArgs holds the arguments of a go test invocation, split into flags,
+// package patterns and the arguments passed to the test binary after -args,
+// together with extra environment variables (KEY=VALUE) for the go command
+type TestArgs struct {
+	Flags      []string
+	Packages   []string
+	BinaryArgs []string
+	Env        []string
+}
+
+// boolFlags lists the go test and build flags that do not take a separate value
+var boolFlags = map[string]bool{
+	"a": true, "asan": true, "benchmem": true, "c": true, "cover": true,
+	"failfast": true, "fullpath": true, "i": true, "json": true, "linkshared": true,
+	"modcacherw": true, "msan": true, "n": true, "race": true, "short": true,
+	"trimpath": true, "v": true, "work": true, "x": true,
+}
+
+// ParseTestArgs splits go test command line arguments into flags, packages and test binary arguments
+func ParseTestArgs(args []string) TestArgs {
+	var ta TestArgs
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			ta.Packages = append(ta.Packages, arg)
+			continue
+		}
+
+		name, hasValue := flagName(arg)
+		if name == "args" {
+			ta.BinaryArgs = append(ta.BinaryArgs, args[i+1:]...)
+			break
+		}
+		ta.Flags = append(ta.Flags, arg)
+		if !hasValue && !boolFlags[name] && i+1 < len(args) {
+			i++
+			ta.Flags = append(ta.Flags, args[i])
+		}
+	}
+	return ta
+}
+
+// Clone returns a deep copy of the arguments
+func (ta TestArgs) Clone() TestArgs {
+	return TestArgs{
+		Flags:      append([]string(nil), ta.Flags...),
+		Packages:   append([]string(nil), ta.Packages...),
+		BinaryArgs: append([]string(nil), ta.BinaryArgs...),
+		Env:        append([]string(nil), ta.Env...),
+	}
+}
+
+// FlagValue returns the value of the last occurrence of a flag.
+// Boolean flags given without a value return "true".
+func (ta TestArgs) FlagValue(name string) (string, bool) {
+	value, found := "", false
+	for i := 0; i < len(ta.Flags); i++ {
+		n, hasValue := flagName(ta.Flags[i])
+		switch {
+		case hasValue:
+			if n == name {
+				value, found = ta.Flags[i][strings.Index(ta.Flags[i], "=")+1:], true
+			}
+		case boolFlags[n]:
+			if n == name {
+				value, found = "true", true
+			}
+		case i+1 < len(ta.Flags):
+			i++
+			if n == name {
+				value, found = ta.Flags[i], true
+			}
+		}
+	}
+	return value, found
+}
+
+// SetFlag replaces every occurrence of a flag with the given value.
+// An empty value (or "false" for boolean flags) removes the flag.
+func (ta *TestArgs) SetFlag(name, value string) {
+	ta.Flags = withoutFlag(ta.Flags, name)
+	switch {
+	case value == "", boolFlags[name] && value == "false":
+		// Already removed
+	case boolFlags[name] && value == "true":
+		ta.Flags = append(ta.Flags, "-"+name)
+	default:
+		ta.Flags = append(ta.Flags, "-"+name+"="+value)
+	}
+}
+
+// OptionString returns the environment and flags of the arguments, without packages
+func (ta TestArgs) OptionString() string {
+	return strings.Join(append(append([]string(nil), ta.Env...), ta.Flags...), " ")
+}
+
+// CommandArgs returns the go command arguments, always requesting JSON output
+func (ta TestArgs) CommandArgs() []string {
+	args := []string{"test", "-json"}
+	args = append(args, withoutFlag(ta.Flags, "json")...)
+	args = append(args, ta.Packages...)
+	if len(ta.BinaryArgs) > 0 {
+		args = append(args, "-args")
+		args = append(args, ta.BinaryArgs...)
+	}
+	return args
+}
+
+// String returns the arguments as they would be typed after "go test"
+func (ta TestArgs) String() string {
+	args := append([]string(nil), ta.Flags...)
+	args = append(args, ta.Packages...)
+	if len(ta.BinaryArgs) > 0 {
+		args = append(args, "-args")
+		args = append(args, ta.BinaryArgs...)
+	}
+	return strings.Join(args, " ")
+}
+
+// flagName returns the name of a flag argument and whether the value is attached with "="
+func flagName(arg string) (string, bool) {
+	name := strings.TrimLeft(arg, "-")
+	name = strings.TrimPrefix(name, "test.")
+	if idx := strings.Index(name, "="); idx >= 0 {
+		return name[:idx], true
+	}
+	return name, false
+}
+
+// withoutFlag returns flags with every occurrence of the named flag (and its value) removed
+func withoutFlag(flags []string, name string) []string {
+	var result []string
+	for i := 0; i < len(flags); i++ {
+		n, hasValue := flagName(flags[i])
+		if n != name {
+			result = append(result, flags[i])
+			continue
+		}
+		if !hasValue && !boolFlags[n] {
+			i++ // Skip the separate value
+		}
+	}
+	return result
+}
+
+// SplitWords splits a command line into words the way a POSIX shell does, honouring single
+// quotes, double quotes and backslash escapes, without any expansion
+func SplitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			inWord = true
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+			for i++; ; i++ {
+				if i >= len(s) {
+					return nil, errors.New("unterminated double quote")
+				}
+				if s[i] == '"' {
+					break
+				}
+				// Inside double quotes a backslash only escapes characters special there
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// JoinWords joins words into a command line SplitWords splits back into the same words,
+// single-quoting the words with spaces or shell metacharacters
+func JoinWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		if word != "" && !strings.ContainsAny(word, " \t\n'\"\\$`|&;<>()*?[]#~{}!") {
+			quoted[i] = word
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
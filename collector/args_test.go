@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"  -v  -race ", []string{"-v", "-race"}},
+		{"-run 'A B'", []string{"-run", "A B"}},
+		{`GOFLAGS="-tags=a -race" CGO_ENABLED=0`, []string{"GOFLAGS=-tags=a -race", "CGO_ENABLED=0"}},
+		{`-run=^Test\ A$`, []string{"-run=^Test A$"}},
+		{`"a \"b\" \c" 'it'\''s'`, []string{`a "b" \c`, "it's"}},
+		{"''", []string{""}},
+	}
+	for _, tt := range tests {
+		got, err := SplitWords(tt.input)
+		if err != nil {
+			t.Errorf("SplitWords(%q) error: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitWords(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if len(got) > 0 {
+			if back, _ := SplitWords(JoinWords(got)); !reflect.DeepEqual(back, got) {
+				t.Errorf("SplitWords(JoinWords(%q)) = %q", got, back)
+			}
+		}
+	}
+
+	for _, input := range []string{"-run 'A B", `-run "A B`} {
+		if _, err := SplitWords(input); err == nil {
+			t.Errorf("SplitWords(%q) succeeded, want an unterminated quote error", input)
+		}
+	}
+}
@@ -2,41 +2,86 @@ package collector
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"strings"
 	"time"
 )
 
 type Action string
 
+// Actions emitted by go test -json (see go doc cmd/test2json) and go build -json
 const (
-	ActionRun    Action = "run"
-	ActionPass   Action = "pass"
-	ActionFail   Action = "fail"
-	ActionSkip   Action = "skip"
-	ActionOutput Action = "output"
-	ActionStart  Action = "start"
+	ActionRun         Action = "run"
+	ActionPass        Action = "pass"
+	ActionFail        Action = "fail"
+	ActionSkip        Action = "skip"
+	ActionOutput      Action = "output"
+	ActionStart       Action = "start"
+	ActionPause       Action = "pause"
+	ActionCont        Action = "cont"
+	ActionBench       Action = "bench"
+	ActionBuildOutput Action = "build-output"
+	ActionBuildFail   Action = "build-fail"
 )
 
+// ANSIPattern matches the ANSI escape sequences colored test output is full of: CSI sequences
+// (group 1 holds the parameters and group 2 the final byte), OSC strings, other two-byte escapes
+// and stray escape characters
+var ANSIPattern = regexp.MustCompile(`\x1b\[([0-?]*)[ -/]*([@-~])|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)?|\x1b[@-_]?`)
+
+// stripANSI removes the ANSI escape sequences of test output
+func stripANSI(output string) string {
+	return ANSIPattern.ReplaceAllString(output, "")
+}
+
 type TestEvent struct {
-	Time    time.Time `json:"Time"`
-	Action  Action    `json:"Action"`
-	Package string    `json:"Package"`
-	Test    string    `json:"Test,omitempty"`
-	Elapsed float64   `json:"Elapsed,omitempty"`
-	Output  string    `json:"Output,omitempty"`
+	Time        time.Time `json:"Time"`
+	Action      Action    `json:"Action"`
+	Package     string    `json:"Package"`
+	Test        string    `json:"Test,omitempty"`
+	Elapsed     float64   `json:"Elapsed,omitempty"`
+	Output      string    `json:"Output,omitempty"`
+	OutputType  string    `json:"OutputType,omitempty"`
+	FailedBuild string    `json:"FailedBuild,omitempty"`
+	ImportPath  string    `json:"ImportPath,omitempty"` // Set on build events instead of Package
+	Worker      int       `json:"-"`                    // Index of the go test process of a stress run
 }
 
 func (te *TestEvent) IsRootEvent() bool {
 	return te.Test == ""
 }
 
-type Results struct {
-	Passed  int
-	Failed  int
-	Skipped int
+// IsBuildEvent reports whether the event comes from the build rather than a test binary
+func (te *TestEvent) IsBuildEvent() bool {
+	return te.Action == ActionBuildOutput || te.Action == ActionBuildFail
+}
+
+// IsTerminal reports whether the action finishes a test or package
+func (a Action) IsTerminal() bool {
+	switch a {
+	case ActionPass, ActionFail, ActionSkip, ActionBench:
+		return true
+	}
+	return false
+}
+
+// TestKey identifies a test by its package and full test name.
+// Package-level events have an empty Test.
+type TestKey struct {
+	Package string
+	Test    string
+}
+
+// Key returns the identity of the test the event belongs to
+func (te *TestEvent) Key() TestKey {
+	return TestKey{Package: te.Package, Test: te.Test}
 }
 
 func UnmarshalTestEvent(b []byte) (TestEvent, error) {
@@ -91,8 +136,17 @@ func ImportEvents(filename string) ([]TestEvent, error) {
 	}
 	defer file.Close()
 
+	events, err := ReadEvents(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return events, nil
+}
+
+// ReadEvents reads all test events from r (one event per line, same as go test -json)
+func ReadEvents(r io.Reader) ([]TestEvent, error) {
 	var events []TestEvent
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		te, err := UnmarshalTestEvent(scanner.Bytes())
 		if err != nil {
@@ -102,24 +156,75 @@ func ImportEvents(filename string) ([]TestEvent, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
 	return events, nil
 }
 
 // RunPackage executes all tests in a package and sends events to the channel
-func RunPackage(pkg string, eventChan chan<- TestEvent) error {
-	return runGoTest(eventChan, "go", "test", "-json", pkg)
+func RunPackage(ctx context.Context, pkg string, args TestArgs, eventChan chan<- TestEvent) error {
+	args = args.Clone()
+	args.Packages = []string{pkg}
+	return Run(ctx, args, eventChan)
 }
 
 // RunTest executes a specific test and sends events to the channel
-func RunTest(pkg string, testName string, eventChan chan<- TestEvent) error {
-	return runGoTest(eventChan, "go", "test", "-json", "-run", "^"+testName+"$", pkg)
+func RunTest(ctx context.Context, pkg string, testName string, args TestArgs, eventChan chan<- TestEvent) error {
+	return RunTests(ctx, pkg, []string{testName}, args, eventChan)
+}
+
+// RunTests executes the given tests of a package in a single go test and sends events to the channel
+func RunTests(ctx context.Context, pkg string, testNames []string, args TestArgs, eventChan chan<- TestEvent) error {
+	args = args.Clone()
+	args.Packages = []string{pkg}
+	args.Flags = append(withoutFlag(args.Flags, "run"), "-run", runPattern(testNames))
+	return Run(ctx, args, eventChan)
 }
 
-// runGoTest executes a go test command and streams events to the channel
-func runGoTest(eventChan chan<- TestEvent, args ...string) error {
-	cmd := exec.Command(args[0], args[1:]...)
+// runPattern returns an anchored -run pattern matching exactly the given tests.
+// go test splits -run on unbracketed "/" and matches each element against one level
+// of the test name, so subtests get one escaped, anchored expression per level, and
+// several tests are combined with a top-level "|".
+func runPattern(testNames []string) string {
+	var topLevel, subtests []string
+	for _, name := range testNames {
+		levels := strings.Split(name, "/")
+		if len(levels) == 1 {
+			topLevel = append(topLevel, quoteTestName(name))
+			continue
+		}
+		for i, level := range levels {
+			levels[i] = "^" + quoteTestName(level) + "$"
+		}
+		subtests = append(subtests, strings.Join(levels, "/"))
+	}
+
+	var alternatives []string
+	if len(topLevel) > 0 {
+		alternatives = append(alternatives, "^("+strings.Join(topLevel, "|")+")$")
+	}
+	return strings.Join(append(alternatives, subtests...), "|")
+}
+
+// quoteTestName escapes one level of a test name for use in a -run pattern.
+// Spaces are rewritten to underscores the same way the testing package names subtests.
+func quoteTestName(name string) string {
+	return regexp.QuoteMeta(strings.ReplaceAll(name, " ", "_"))
+}
+
+// Run executes go test with the given arguments and sends events to the channel
+// Cancelling ctx kills go test together with the test binaries it started and returns ctx.Err().
+func Run(ctx context.Context, args TestArgs, eventChan chan<- TestEvent) error {
+	return runGoTest(ctx, eventChan, args.Env, append([]string{"go"}, args.CommandArgs()...)...)
+}
+
+// runGoTest executes a go test command with extra environment variables and streams events to the channel
+func runGoTest(ctx context.Context, eventChan chan<- TestEvent, env []string, args ...string) error {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	setProcessGroup(cmd)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
@@ -136,10 +241,13 @@ func runGoTest(eventChan chan<- TestEvent, args ...string) error {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to parse JSON: %v\n", err)
 			continue
 		}
-		eventChan <- te
+		Send(ctx, eventChan, te)
 	}
 
 	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		// Test failures result in ExitError, which is expected
 		if _, ok := err.(*exec.ExitError); !ok {
 			return fmt.Errorf("test command failed: %w", err)
@@ -148,3 +256,46 @@ func runGoTest(eventChan chan<- TestEvent, args ...string) error {
 
 	return nil
 }
+
+// Send sends v to the channel, or drops it once ctx is cancelled, so that the producer can keep
+// draining its input without blocking on a receiver that may be gone
+func Send[T any](ctx context.Context, ch chan<- T, v T) {
+	select {
+	case ch <- v:
+	case <-ctx.Done():
+	}
+}
+
+// PackageDir returns the source directory of a package
+func PackageDir(ctx context.Context, args TestArgs, pkg string) (string, error) {
+	out, err := goCommand(ctx, args.Env, "list", "-e", "-f", "{{.Dir}}", pkg)
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(out)
+	if dir == "" {
+		return "", fmt.Errorf("no directory found for package %s", pkg)
+	}
+	return dir, nil
+}
+
+// goCommand runs the go command with extra environment variables and returns its standard output
+func goCommand(ctx context.Context, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("go %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("go %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
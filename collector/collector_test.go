@@ -0,0 +1,59 @@
+package collector
+
+import "testing"
+
+func TestRunPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		testNames []string
+		want      string
+	}{
+		{
+			name:      "single top-level test",
+			testNames: []string{"TestFoo"},
+			want:      `^(TestFoo)$`,
+		},
+		{
+			name:      "several top-level tests",
+			testNames: []string{"TestFoo", "TestBar"},
+			want:      `^(TestFoo|TestBar)$`,
+		},
+		{
+			name:      "subtest",
+			testNames: []string{"TestFoo/case_1"},
+			want:      `^TestFoo$/^case_1$`,
+		},
+		{
+			name:      "nested subtest",
+			testNames: []string{"TestFoo/group/case_1"},
+			want:      `^TestFoo$/^group$/^case_1$`,
+		},
+		{
+			name:      "metacharacters",
+			testNames: []string{"TestFoo/a.b*c+d?(e)[f]{g}|h^i$j\\k"},
+			want:      `^TestFoo$/^a\.b\*c\+d\?\(e\)\[f\]\{g\}\|h\^i\$j\\k$`,
+		},
+		{
+			name:      "spaces become underscores",
+			testNames: []string{"TestFoo/example test 01"},
+			want:      `^TestFoo$/^example_test_01$`,
+		},
+		{
+			name:      "subtests of different parents",
+			testNames: []string{"TestFoo/x", "TestBar/y/z", "TestFoo/w"},
+			want:      `^TestFoo$/^x$|^TestBar$/^y$/^z$|^TestFoo$/^w$`,
+		},
+		{
+			name:      "top-level tests and subtests",
+			testNames: []string{"TestFoo", "TestBar/y", "TestBaz"},
+			want:      `^(TestFoo|TestBaz)$|^TestBar$/^y$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runPattern(tt.testNames); got != tt.want {
+				t.Errorf("runPattern(%q) = %s, want %s", tt.testNames, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"time"
+)
+
+//go:embed report.html
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"statusIcon":  statusIcon,
+	"statusClass": statusClass,
+	"lastName":    lastName,
+	"stripANSI":   stripANSI,
+}).Parse(reportTemplateText))
+
+// htmlReport is the data rendered by the HTML report template
+type htmlReport struct {
+	Results   *Results
+	Total     int
+	Generated string
+}
+
+// ExportHTML exports test events as a self-contained HTML report with a collapsible
+// package/test tree, per-test logs and a search box
+func ExportHTML(filename string, events []TestEvent) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	results := CollectResults(events)
+	report := htmlReport{
+		Results:   results,
+		Total:     results.Passed + results.Failed + results.Skipped,
+		Generated: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if err := reportTemplate.Execute(file, report); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// statusIcon returns the icon shown for a terminal action
+func statusIcon(action Action) string {
+	switch action {
+	case ActionPass, ActionBench:
+		return "✓"
+	case ActionFail:
+		return "✗"
+	case ActionSkip:
+		return "⏭"
+	default:
+		return "⧗"
+	}
+}
+
+// statusClass returns the CSS class of a terminal action
+func statusClass(action Action) string {
+	switch action {
+	case ActionPass, ActionBench:
+		return "pass"
+	case ActionFail:
+		return "fail"
+	case ActionSkip:
+		return "skip"
+	default:
+		return "run"
+	}
+}
@@ -0,0 +1,200 @@
+package collector
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the test cases of a single package
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemOut *junitOutput    `xml:"system-out,omitempty"`
+}
+
+// junitTestCase holds the outcome of a single test or subtest
+type junitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+// junitMessage is the body of a failure, error or skipped element
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",cdata"`
+}
+
+// junitOutput is the body of a system-out element
+type junitOutput struct {
+	Contents string `xml:",cdata"`
+}
+
+// newJUnitOutput returns a system-out element, or nil when there is no output
+func newJUnitOutput(output string) *junitOutput {
+	if output == "" {
+		return nil
+	}
+	return &junitOutput{Contents: junitText(output)}
+}
+
+// newJUnitMessage returns a failure, error or skipped element
+func newJUnitMessage(message, output string) *junitMessage {
+	return &junitMessage{Message: junitText(message), Contents: junitText(output)}
+}
+
+// junitText strips ANSI escape sequences from test output and replaces the remaining
+// characters XML 1.0 forbids, so that the report stays well-formed
+func junitText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20, r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF:
+			return utf8.RuneError
+		}
+		return r
+	}, stripANSI(s))
+}
+
+// ExportJUnit exports test events as a JUnit XML report. Each package becomes a testsuite
+// and every test, including nested subtests, becomes a testcase named by its full test name.
+func ExportJUnit(filename string, events []TestEvent) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(xml.Header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(buildJUnit(CollectResults(events))); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return nil
+}
+
+// buildJUnit converts test results to the JUnit XML document
+func buildJUnit(r *Results) junitTestSuites {
+	var suites junitTestSuites
+	var totalElapsed float64
+	for _, pr := range r.Packages {
+		suite := junitTestSuite{
+			Name: pr.Package,
+			Time: junitTime(pr.Elapsed),
+		}
+		for _, tr := range pr.Tests {
+			appendJUnitCases(&suite, pr, tr)
+		}
+
+		// A build failure or a failure outside of any test is reported as a suite-level error
+		if pr.BuildFailed() || (pr.Action == ActionFail && suite.Failures == 0) {
+			message := "package failed"
+			if pr.BuildFailed() {
+				message = "build failed"
+			}
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Classname: pr.Package,
+				Name:      "[package]",
+				Time:      junitTime(pr.Elapsed),
+				Error:     newJUnitMessage(message, pr.Output),
+			})
+			suite.Tests++
+			suite.Errors++
+		} else {
+			suite.SystemOut = newJUnitOutput(pr.Output)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		totalElapsed += pr.Elapsed
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = junitTime(totalElapsed)
+	return suites
+}
+
+// appendJUnitCases adds a testcase for every run of tr and of all of its subtests to the suite
+func appendJUnitCases(suite *junitTestSuite, pr *PackageResult, tr *TestResult) {
+	if len(tr.Attempts) > 0 {
+		for _, attempt := range tr.Attempts {
+			appendJUnitCase(suite, pr, attempt)
+		}
+	} else {
+		appendJUnitCase(suite, pr, tr)
+	}
+
+	for _, sub := range tr.Subtests {
+		appendJUnitCases(suite, pr, sub)
+	}
+}
+
+// appendJUnitCase adds a testcase for a single run of a test to the suite
+func appendJUnitCase(suite *junitTestSuite, pr *PackageResult, tr *TestResult) {
+	tc := junitTestCase{
+		Classname: pr.Package,
+		Name:      tr.Name,
+		Time:      junitTime(tr.Elapsed),
+	}
+	switch tr.Action {
+	case ActionFail:
+		tc.Failure = newJUnitMessage("Failed", tr.Output)
+		suite.Failures++
+	case ActionSkip:
+		tc.Skipped = newJUnitMessage(skipMessage(tr.Output), tr.Output)
+		suite.Skipped++
+	case "":
+		// The test binary exited before the test finished
+		tc.Error = newJUnitMessage("test did not finish", tr.Output)
+		suite.Errors++
+	default:
+		tc.SystemOut = newJUnitOutput(tr.Output)
+	}
+	suite.TestCases = append(suite.TestCases, tc)
+	suite.Tests++
+}
+
+// skipMessage returns the reason given to t.Skip, if any
+func skipMessage(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "=== ") || strings.HasPrefix(line, "--- SKIP") {
+			continue
+		}
+		return line
+	}
+	return "Skipped"
+}
+
+// junitTime formats an elapsed time in seconds
+func junitTime(elapsed float64) string {
+	return fmt.Sprintf("%.3f", elapsed)
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportJUnitWellFormed(t *testing.T) {
+	events := []TestEvent{
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestColor"},
+		{Action: ActionOutput, Package: "example.com/pkg", Test: "TestColor", Output: "    \x1b[31mred\x1b[0m\x1b[K\n"},
+		{Action: ActionOutput, Package: "example.com/pkg", Test: "TestColor", Output: "    bell\x07 nul\x00 invalid\xff ]]> end\n"},
+		{Action: ActionFail, Package: "example.com/pkg", Test: "TestColor", Elapsed: 0.5},
+		{Action: ActionRun, Package: "example.com/pkg", Test: "TestSkip"},
+		{Action: ActionOutput, Package: "example.com/pkg", Test: "TestSkip", Output: "    \x1b[33mnot on CI\x1b[0m\n"},
+		{Action: ActionSkip, Package: "example.com/pkg", Test: "TestSkip"},
+		{Action: ActionOutput, Package: "example.com/pkg", Output: "\x1b[1mFAIL\x1b[0m\n"},
+		{Action: ActionFail, Package: "example.com/pkg", Elapsed: 0.6},
+	}
+
+	filename := filepath.Join(t.TempDir(), "report.xml")
+	if err := ExportJUnit(filename, events); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not well-formed: %v\n%s", err, data)
+	}
+	if len(report.Suites) != 1 || len(report.Suites[0].TestCases) != 2 {
+		t.Fatalf("unexpected report structure: %+v", report)
+	}
+
+	failure := report.Suites[0].TestCases[0].Failure
+	if failure == nil {
+		t.Fatal("TestColor has no failure element")
+	}
+	for _, want := range []string{"red\n", "bell� nul� invalid� ]]> end"} {
+		if !strings.Contains(failure.Contents, want) {
+			t.Errorf("failure contents %q do not contain %q", failure.Contents, want)
+		}
+	}
+	if strings.Contains(failure.Contents, "\x1b") {
+		t.Errorf("failure contents %q still contain escape sequences", failure.Contents)
+	}
+
+	skipped := report.Suites[0].TestCases[1].Skipped
+	if skipped == nil || skipped.Message != "not on CI" {
+		t.Errorf("skipped message = %+v, want %q", skipped, "not on CI")
+	}
+}
+
+func TestBuildJUnitAttempts(t *testing.T) {
+	suites := buildJUnit(CollectResults(repeatedEvents()))
+	suite := suites.Suites[0]
+	if suite.Tests != 2 || suite.Failures != 1 {
+		t.Errorf("tests, failures = %d, %d, want 2, 1", suite.Tests, suite.Failures)
+	}
+	if len(suite.TestCases) != 2 || suite.TestCases[0].Failure == nil || suite.TestCases[1].Failure != nil {
+		t.Fatalf("want a failed then a passed testcase, got %+v", suite.TestCases)
+	}
+	if !strings.Contains(suite.TestCases[0].Failure.Contents, "first run broke") {
+		t.Errorf("failure contents = %q", suite.TestCases[0].Failure.Contents)
+	}
+}
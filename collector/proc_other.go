@@ -0,0 +1,9 @@
+//go:build !unix
+
+package collector
+
+import "os/exec"
+
+// setProcessGroup is a no-op on platforms without process groups; cancellation
+// kills only the go command itself
+func setProcessGroup(cmd *exec.Cmd) {}
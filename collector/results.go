@@ -0,0 +1,303 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TestResult is the outcome of a single test and its subtests
+type TestResult struct {
+	Name     string // Full test name including parent tests
+	Action   Action // Last terminal action, empty while the test has not finished, fail when any attempt failed
+	Elapsed  float64
+	Output   string
+	Attempt  int           // Number of the run, from 1, for the results in Attempts
+	Attempts []*TestResult // Runs of a test repeated with -count, nil when it ran once
+	Subtests []*TestResult
+}
+
+// PackageResult is the outcome of a package and its top-level tests
+type PackageResult struct {
+	Package     string
+	Action      Action // Last terminal action, empty while the package has not finished
+	Elapsed     float64
+	Output      string // Package-level output, preceded by the output of a failed build
+	FailedBuild string
+	Tests       []*TestResult
+}
+
+// BuildFailed reports whether the package failed because it could not be built
+func (pr *PackageResult) BuildFailed() bool {
+	return pr.FailedBuild != "" || strings.Contains(pr.Output, "[build failed]")
+}
+
+// Results aggregates the outcome of a test run. Passed, Failed and Skipped count
+// leaf tests (tests without subtests); BuildFailed counts packages.
+type Results struct {
+	Passed      int
+	Failed      int
+	Skipped     int
+	BuildFailed int
+	Packages    []*PackageResult
+}
+
+// HasFailures reports whether any test or package failed
+func (r *Results) HasFailures() bool {
+	if r.Failed > 0 || r.BuildFailed > 0 {
+		return true
+	}
+	for _, pr := range r.Packages {
+		if pr.Action == ActionFail {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectResults builds the results of a test run from its events, in stream order
+func CollectResults(events []TestEvent) *Results {
+	r := &Results{}
+	packages := make(map[string]*PackageResult)
+	tests := make(map[TestKey]*TestResult)
+	testEvents := make(map[*TestResult][]TestEvent)
+	buildOutput := make(map[string]string)
+
+	for _, te := range events {
+		if te.IsBuildEvent() {
+			buildOutput[te.ImportPath] += te.Output
+			continue
+		}
+		if te.Package == "" {
+			continue
+		}
+
+		pr, ok := packages[te.Package]
+		if !ok {
+			pr = &PackageResult{Package: te.Package}
+			packages[te.Package] = pr
+			r.Packages = append(r.Packages, pr)
+		}
+
+		if te.IsRootEvent() {
+			switch {
+			case te.Action == ActionOutput:
+				pr.Output += te.Output
+			case te.Action.IsTerminal():
+				pr.Action = te.Action
+				pr.Elapsed = te.Elapsed
+				if te.FailedBuild != "" {
+					pr.FailedBuild = te.FailedBuild
+					pr.Output = buildOutput[te.FailedBuild] + pr.Output
+				}
+			}
+			continue
+		}
+
+		tr := testResult(pr, tests, te.Test)
+		testEvents[tr] = append(testEvents[tr], te)
+		switch {
+		case te.Action == ActionOutput:
+			tr.Output += te.Output
+		case te.Action.IsTerminal():
+			tr.Action = te.Action
+			tr.Elapsed = te.Elapsed
+		}
+	}
+
+	for tr, events := range testEvents {
+		tr.setAttempts(events)
+	}
+	for _, pr := range r.Packages {
+		if pr.BuildFailed() {
+			r.BuildFailed++
+		}
+		for _, tr := range pr.Tests {
+			r.count(pr, tr)
+		}
+	}
+	return r
+}
+
+// testResult returns the result for a test, creating it and its parents as needed
+func testResult(pr *PackageResult, tests map[TestKey]*TestResult, name string) *TestResult {
+	key := TestKey{Package: pr.Package, Test: name}
+	if tr, ok := tests[key]; ok {
+		return tr
+	}
+
+	tr := &TestResult{Name: name}
+	tests[key] = tr
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		parent := testResult(pr, tests, name[:idx])
+		parent.Subtests = append(parent.Subtests, tr)
+	} else {
+		pr.Tests = append(pr.Tests, tr)
+	}
+	return tr
+}
+
+// SplitAttempts splits the events of a test into its runs, as repeated by -count, in the order
+// they started. Each attempt starts with a run event and gets the following events of the same
+// go test process, as the processes of a stress run interleave their events.
+func SplitAttempts(events []TestEvent) [][]TestEvent {
+	var attempts [][]TestEvent
+	current := make(map[int]int) // Attempt in progress of each process
+	for _, te := range events {
+		i, ok := current[te.Worker]
+		if te.Action == ActionRun || !ok {
+			attempts = append(attempts, nil)
+			i = len(attempts) - 1
+			current[te.Worker] = i
+		}
+		attempts[i] = append(attempts[i], te)
+	}
+	return attempts
+}
+
+// setAttempts records the runs of a test repeated with -count. The test is unfinished while
+// its last attempt is and failed when any attempt failed.
+func (tr *TestResult) setAttempts(events []TestEvent) {
+	attempts := SplitAttempts(events)
+	if len(attempts) < 2 {
+		return
+	}
+	failed := false
+	for i, events := range attempts {
+		attempt := &TestResult{Name: tr.Name, Attempt: i + 1}
+		for _, te := range events {
+			switch {
+			case te.Action == ActionOutput:
+				attempt.Output += te.Output
+			case te.Action.IsTerminal():
+				attempt.Action = te.Action
+				attempt.Elapsed = te.Elapsed
+			}
+		}
+		failed = failed || attempt.Action == ActionFail
+		tr.Attempts = append(tr.Attempts, attempt)
+	}
+	switch last := tr.Attempts[len(tr.Attempts)-1]; {
+	case last.Action == "":
+		tr.Action = ""
+	case failed:
+		tr.Action = ActionFail
+	}
+}
+
+// count adds the leaf tests below tr to the totals
+func (r *Results) count(pr *PackageResult, tr *TestResult) {
+	if len(tr.Subtests) > 0 {
+		for _, sub := range tr.Subtests {
+			r.count(pr, sub)
+		}
+		return
+	}
+	switch tr.Action {
+	case ActionPass, ActionBench:
+		r.Passed++
+	case ActionFail:
+		r.Failed++
+	case ActionSkip:
+		r.Skipped++
+	case "":
+		// A test that never finished in a failed package was most likely killed by a panic or timeout
+		if pr.Action == ActionFail {
+			r.Failed++
+		}
+	}
+}
+
+// WriteSummary writes a compact tree of the failed packages and tests with their logs,
+// followed by the totals
+func WriteSummary(w io.Writer, r *Results) error {
+	var b strings.Builder
+	for _, pr := range r.Packages {
+		if pr.Action != ActionFail && !pr.BuildFailed() {
+			continue
+		}
+
+		note := ""
+		if pr.BuildFailed() {
+			note = " (build failed)"
+		}
+		fmt.Fprintf(&b, "✗ %s%s [%.3fs]\n", pr.Package, note, pr.Elapsed)
+
+		failedTests := false
+		for _, tr := range pr.Tests {
+			if writeFailedTest(&b, pr, tr, 1) {
+				failedTests = true
+			}
+		}
+		// Without a failing test the cause is in the package output (build errors, panics in TestMain, ...)
+		if !failedTests {
+			writeLog(&b, pr.Output, 2)
+		}
+		b.WriteString("\n")
+	}
+
+	total := r.Passed + r.Failed + r.Skipped
+	fmt.Fprintf(&b, "DONE %d tests: %d passed, %d failed, %d skipped", total, r.Passed, r.Failed, r.Skipped)
+	if r.BuildFailed > 0 {
+		fmt.Fprintf(&b, ", %d build failed", r.BuildFailed)
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFailedTest writes tr if it failed, with the logs of its failed leaf tests,
+// and reports whether anything was written
+func writeFailedTest(b *strings.Builder, pr *PackageResult, tr *TestResult, depth int) bool {
+	unfinished := tr.Action == "" && pr.Action == ActionFail
+	if tr.Action != ActionFail && !unfinished {
+		return false
+	}
+
+	indent := strings.Repeat("  ", depth)
+	fmt.Fprintf(b, "%s✗ %s [%.3fs]\n", indent, lastName(tr.Name), tr.Elapsed)
+
+	failedSubtests := false
+	for _, sub := range tr.Subtests {
+		if writeFailedTest(b, pr, sub, depth+1) {
+			failedSubtests = true
+		}
+	}
+	if failedSubtests {
+		return true
+	}
+	if len(tr.Attempts) == 0 {
+		writeLog(b, tr.Output, depth+1)
+		return true
+	}
+	// Only the logs of the failed runs of a repeated test matter
+	for _, attempt := range tr.Attempts {
+		if attempt.Action == ActionFail || (attempt.Action == "" && pr.Action == ActionFail) {
+			fmt.Fprintf(b, "%s  ✗ #%d [%.3fs]\n", indent, attempt.Attempt, attempt.Elapsed)
+			writeLog(b, attempt.Output, depth+2)
+		}
+	}
+	return true
+}
+
+// writeLog writes output indented below a tree entry, dropping test framing lines
+func writeLog(b *strings.Builder, output string, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" || strings.HasPrefix(line, "=== ") {
+			continue
+		}
+		b.WriteString(indent)
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+}
+
+// lastName returns the last component of a slash-separated test name
+func lastName(name string) string {
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		return name[idx+1:]
+	}
+	return name
+}
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+// repeatedEvents returns the events of go test -count=2 on a test failing once, then passing
+func repeatedEvents() []TestEvent {
+	const pkg = "example.com/pkg"
+	return []TestEvent{
+		{Action: ActionStart, Package: pkg},
+		{Action: ActionRun, Package: pkg, Test: "TestFlaky"},
+		{Action: ActionOutput, Package: pkg, Test: "TestFlaky", Output: "=== RUN   TestFlaky\n"},
+		{Action: ActionOutput, Package: pkg, Test: "TestFlaky", Output: "    flaky_test.go:10: first run broke\n"},
+		{Action: ActionOutput, Package: pkg, Test: "TestFlaky", Output: "--- FAIL: TestFlaky (0.10s)\n"},
+		{Action: ActionFail, Package: pkg, Test: "TestFlaky", Elapsed: 0.1},
+		{Action: ActionRun, Package: pkg, Test: "TestFlaky"},
+		{Action: ActionOutput, Package: pkg, Test: "TestFlaky", Output: "=== RUN   TestFlaky\n"},
+		{Action: ActionOutput, Package: pkg, Test: "TestFlaky", Output: "    flaky_test.go:12: second run fine\n"},
+		{Action: ActionOutput, Package: pkg, Test: "TestFlaky", Output: "--- PASS: TestFlaky (0.20s)\n"},
+		{Action: ActionPass, Package: pkg, Test: "TestFlaky", Elapsed: 0.2},
+		{Action: ActionOutput, Package: pkg, Output: "FAIL\n"},
+		{Action: ActionFail, Package: pkg, Elapsed: 0.3},
+	}
+}
+
+func TestCollectResultsAttempts(t *testing.T) {
+	r := CollectResults(repeatedEvents())
+	if r.Passed != 0 || r.Failed != 1 {
+		t.Errorf("passed, failed = %d, %d, want 0, 1", r.Passed, r.Failed)
+	}
+
+	tr := r.Packages[0].Tests[0]
+	if tr.Action != ActionFail {
+		t.Errorf("action = %q, want %q", tr.Action, ActionFail)
+	}
+	if len(tr.Attempts) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(tr.Attempts))
+	}
+	for i, want := range []struct {
+		action Action
+		output string
+	}{
+		{ActionFail, "first run broke"},
+		{ActionPass, "second run fine"},
+	} {
+		attempt := tr.Attempts[i]
+		if attempt.Attempt != i+1 || attempt.Action != want.action || !strings.Contains(attempt.Output, want.output) {
+			t.Errorf("attempt %d = #%d %q %q, want #%d %q with %q", i, attempt.Attempt, attempt.Action, attempt.Output, i+1, want.action, want.output)
+		}
+	}
+
+	var b strings.Builder
+	if err := WriteSummary(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	summary := b.String()
+	for _, want := range []string{"✗ #1 [0.100s]", "first run broke", "0 passed, 1 failed"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary does not contain %q:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "second run fine") {
+		t.Errorf("summary contains the log of the passing attempt:\n%s", summary)
+	}
+}
+
+func TestSplitAttemptsWorkers(t *testing.T) {
+	const pkg = "example.com/pkg"
+	event := func(worker int, action Action, output string) TestEvent {
+		return TestEvent{Action: action, Package: pkg, Test: "TestStress", Output: output, Worker: worker}
+	}
+	// Two go test processes of a stress run interleave their runs of the test
+	events := []TestEvent{
+		event(0, ActionRun, ""),
+		event(1, ActionRun, ""),
+		event(0, ActionOutput, "worker 0 first\n"),
+		event(1, ActionOutput, "worker 1 first\n"),
+		event(1, ActionFail, ""),
+		event(0, ActionPass, ""),
+		event(1, ActionRun, ""),
+		event(1, ActionOutput, "worker 1 second\n"),
+		event(1, ActionPass, ""),
+	}
+
+	attempts := SplitAttempts(events)
+	want := []struct {
+		output string
+		action Action
+	}{
+		{"worker 0 first\n", ActionPass},
+		{"worker 1 first\n", ActionFail},
+		{"worker 1 second\n", ActionPass},
+	}
+	if len(attempts) != len(want) {
+		t.Fatalf("got %d attempts, want %d", len(attempts), len(want))
+	}
+	for i, attempt := range attempts {
+		var output string
+		var action Action
+		for _, te := range attempt {
+			if te.Worker != attempt[0].Worker {
+				t.Errorf("attempt %d mixes the events of workers %d and %d", i, attempt[0].Worker, te.Worker)
+			}
+			if te.Action == ActionOutput {
+				output += te.Output
+			} else if te.Action.IsTerminal() {
+				action = te.Action
+			}
+		}
+		if output != want[i].output || action != want[i].action {
+			t.Errorf("attempt %d = %q %q, want %q %q", i, output, action, want[i].output, want[i].action)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// StressOptions configures the repeated runs of a test
+type StressOptions struct {
+	Iterations   int  // Total number of runs
+	Parallel     int  // Number of concurrent go test processes
+	UntilFailure bool // Stop every process at the first failure
+}
+
+// StressEvent is a test event of one of the processes of a stress run
+type StressEvent struct {
+	Worker int // Index of the go test process
+	Event  TestEvent
+}
+
+// RunStress runs a test Iterations times, split with -count across Parallel go test processes,
+// and sends the events of every process to the channel. With UntilFailure the processes run
+// with -failfast and are all stopped once the test fails.
+func RunStress(ctx context.Context, pkg, testName string, args TestArgs, opts StressOptions, eventChan chan<- StressEvent) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := max(min(opts.Parallel, opts.Iterations), 1)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		failed   bool
+	)
+	for worker := 0; worker < workers; worker++ {
+		count := opts.Iterations / workers
+		if worker < opts.Iterations%workers {
+			count++
+		}
+		workerArgs := args.Clone()
+		workerArgs.SetFlag("count", strconv.Itoa(count))
+		if opts.UntilFailure {
+			workerArgs.SetFlag("failfast", "true")
+		}
+
+		events := make(chan TestEvent, 100)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			defer close(events)
+			if err := RunTests(ctx, pkg, []string{testName}, workerArgs, events); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+		go func(worker int) {
+			defer wg.Done()
+			for te := range events {
+				te.Worker = worker
+				Send(ctx, eventChan, StressEvent{Worker: worker, Event: te})
+				if opts.UntilFailure && te.Action == ActionFail && te.Test == testName {
+					mu.Lock()
+					failed = true
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// Stopping the other processes after a failure is not an error
+	if failed && errors.Is(firstErr, context.Canceled) {
+		return nil
+	}
+	return firstErr
+}
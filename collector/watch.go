@@ -0,0 +1,224 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// watchInterval is how often the watched files are polled
+	watchInterval = 250 * time.Millisecond
+	// watchDebounce is how long the files must stay unchanged before changes are reported
+	watchDebounce = 500 * time.Millisecond
+)
+
+// fileStamp identifies a version of a watched file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// ModuleRoot returns the directory of the main module, or the working directory outside of a module
+func ModuleRoot(ctx context.Context, args TestArgs) (string, error) {
+	out, err := goCommand(ctx, args.Env, "env", "GOMOD")
+	if err != nil {
+		return "", err
+	}
+	gomod := strings.TrimSpace(out)
+	if gomod == "" || gomod == os.DevNull {
+		return os.Getwd()
+	}
+	return filepath.Dir(gomod), nil
+}
+
+// Watch polls the .go files below root and sends the sorted directories of added, changed
+// or removed files once a burst of changes has settled. It returns when ctx is cancelled.
+func Watch(ctx context.Context, root string, changes chan<- []string) error {
+	prev, err := snapshotGoFiles(root)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string]bool)
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		cur, err := snapshotGoFiles(root)
+		if err != nil {
+			return err
+		}
+		for _, dir := range changedDirs(prev, cur) {
+			pending[dir] = true
+			lastChange = time.Now()
+		}
+		prev = cur
+
+		if len(pending) == 0 || time.Since(lastChange) < watchDebounce {
+			continue
+		}
+		dirs := make([]string, 0, len(pending))
+		for dir := range pending {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		pending = make(map[string]bool)
+
+		select {
+		case changes <- dirs:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// snapshotGoFiles returns the stamps of the .go files below root, skipping the directories
+// the go command ignores
+func snapshotGoFiles(root string) (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may disappear while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		files[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// changedDirs returns the directories containing files that differ between two snapshots
+func changedDirs(prev, cur map[string]fileStamp) []string {
+	var dirs []string
+	for path, stamp := range cur {
+		if old, ok := prev[path]; !ok || old != stamp {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	for path := range prev {
+		if _, ok := cur[path]; !ok {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	return dirs
+}
+
+// AffectedPackages returns the packages matched by the arguments whose directory is one of dirs,
+// or whose tests depend on a package in one of dirs
+func AffectedPackages(ctx context.Context, args TestArgs, dirs []string) ([]string, error) {
+	listArgs := []string{"list", "-e", "-deps", "-test", "-f", "{{.ImportPath}}\t{{.Dir}}\t{{.DepOnly}}\t{{.ForTest}}\t{{join .Deps \" \"}}"}
+	if tags, ok := args.FlagValue("tags"); ok {
+		listArgs = append(listArgs, "-tags="+tags)
+	}
+	listArgs = append(listArgs, args.Packages...)
+	out, err := goCommand(ctx, args.Env, listArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	changedDir := make(map[string]bool)
+	for _, dir := range dirs {
+		changedDir[filepath.Clean(dir)] = true
+	}
+
+	type listedPackage struct {
+		importPath string
+		depOnly    bool
+		forTest    string
+		deps       []string
+	}
+	var listed []listedPackage
+	changed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		p := listedPackage{
+			importPath: basePackage(fields[0]),
+			depOnly:    fields[2] == "true",
+			forTest:    fields[3],
+			deps:       strings.Fields(fields[4]),
+		}
+		if fields[1] != "" && changedDir[filepath.Clean(fields[1])] {
+			changed[p.importPath] = true
+		}
+		listed = append(listed, p)
+	}
+
+	affected := make(map[string]bool)
+	for _, p := range listed {
+		// The generated test main package depends on everything and is not a package of its own
+		if p.depOnly || strings.HasSuffix(p.importPath, ".test") {
+			continue
+		}
+		pkg := p.importPath
+		if p.forTest != "" {
+			pkg = p.forTest
+		}
+		if affected[pkg] {
+			continue
+		}
+		if changed[p.importPath] {
+			affected[pkg] = true
+			continue
+		}
+		for _, dep := range p.deps {
+			if changed[basePackage(dep)] {
+				affected[pkg] = true
+				break
+			}
+		}
+	}
+
+	pkgs := make([]string, 0, len(affected))
+	for pkg := range affected {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs, nil
+}
+
+// basePackage strips the test variant suffix, as in "pkg [pkg.test]", from an import path
+func basePackage(importPath string) string {
+	if idx := strings.Index(importPath, " ["); idx >= 0 {
+		return importPath[:idx]
+	}
+	return importPath
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/shooooooooono/gotestui/collector"
 	"github.com/shooooooooono/gotestui/view"
@@ -17,23 +21,107 @@ func main() {
 	flag.BoolVar(showVersion, "v", false, "Show version")
 	importFile := flag.String("i", "", "Import test events from JSON file")
 	flag.StringVar(importFile, "import", "", "Import test events from JSON file")
+	noTUI := flag.Bool("no-tui", false, "Print a summary of failures instead of starting the TUI, exit non-zero on failure")
+	junitFile := flag.String("junit", "", "Write a JUnit XML report to file (implies -no-tui)")
+	htmlFile := flag.String("html", "", "Write a self-contained HTML report to file (implies -no-tui)")
+	watch := flag.Bool("watch", false, "Rerun the packages affected by changed .go files in a new history")
+	selectFailure := flag.Bool("select-failure", false, "Select the first failed test when a run finishes")
+	flag.Usage = usage
 	flag.Parse()
 
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "report" {
+		*noTUI = true
+		// Accept gotestui flags after the subcommand as well
+		if err := flag.CommandLine.Parse(args[1:]); err != nil {
+			os.Exit(2)
+		}
+		args = flag.Args()
+	}
+
 	if *showVersion {
 		fmt.Printf("gotestui %s\n", version)
 		return
 	}
 
+	testArgs, runMode := parseRunArgs(args)
+	var exports []reportExport
+	if *junitFile != "" {
+		exports = append(exports, reportExport{name: "JUnit", filename: *junitFile, export: collector.ExportJUnit})
+	}
+	if *htmlFile != "" {
+		exports = append(exports, reportExport{name: "HTML", filename: *htmlFile, export: collector.ExportHTML})
+	}
+	if *watch && (!runMode || *noTUI || len(exports) > 0) {
+		fmt.Fprintln(os.Stderr, "Error: -watch needs packages to run and the TUI. Usage: gotestui -watch <packages> [go test flags]")
+		os.Exit(2)
+	}
+	if *noTUI || len(exports) > 0 {
+		os.Exit(report(testArgs, runMode, *importFile, exports))
+	}
+
 	eventChan := make(chan collector.TestEvent, 1000) // Buffered to prevent sender blocking
 	doneChan := make(chan struct{})
 
-	if *importFile != "" {
+	// go test runs in its own process group, so stop the TUI and every run on the signals
+	// that would otherwise leave it orphaned
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGHUP, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(sigCtx)
+	defer cancel()
+
+	opts := view.Options{Context: sigCtx, SelectFailure: *selectFailure}
+	if runMode {
+		opts.TestArgs = &testArgs
+		opts.Cancel = cancel
+		opts.Watch = *watch
+		go runTests(ctx, testArgs, eventChan, doneChan)
+	} else if *importFile != "" {
 		go importFromFile(*importFile, eventChan, doneChan)
 	} else {
 		go readFromStdin(eventChan, doneChan)
 	}
 
-	view.CreateApplication(eventChan, doneChan)
+	view.CreateApplication(eventChan, doneChan, opts)
+
+	if runMode {
+		// Stop the initial go test if it is still running and wait for it to exit
+		cancel()
+		<-doneChan
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  go test -json ./... | gotestui")
+	fmt.Fprintln(out, "  gotestui [flags] <packages> [go test flags]")
+	fmt.Fprintln(out, "  gotestui [flags] run -- [go test flags] <packages>")
+	fmt.Fprintln(out, "  gotestui report [flags] [<packages> [go test flags]]")
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// parseRunArgs returns the go test arguments when gotestui should launch the tests itself
+func parseRunArgs(args []string) (collector.TestArgs, bool) {
+	if len(args) == 0 {
+		return collector.TestArgs{}, false
+	}
+	if args[0] == "run" {
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	return collector.ParseTestArgs(args), true
+}
+
+func runTests(ctx context.Context, args collector.TestArgs, eventChan chan<- collector.TestEvent, doneChan chan struct{}) {
+	defer close(doneChan)
+
+	if err := collector.Run(ctx, args, eventChan); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "Error running go test: %v\n", err)
+	}
 }
 
 func importFromFile(filename string, eventChan chan<- collector.TestEvent, doneChan chan struct{}) {
@@ -66,3 +154,70 @@ func isPipedInput() bool {
 	}
 	return (fi.Mode() & os.ModeCharDevice) == 0
 }
+
+// reportExport is a report file requested on the command line
+type reportExport struct {
+	name     string
+	filename string
+	export   func(filename string, events []collector.TestEvent) error
+}
+
+// report collects events without the TUI, prints a summary of failures and returns the exit code
+func report(testArgs collector.TestArgs, runMode bool, importFile string, exports []reportExport) int {
+	var events []collector.TestEvent
+	var err error
+	switch {
+	case runMode:
+		// go test runs in its own process group, so forward interrupts by cancelling it
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		events, err = collectRun(ctx, testArgs)
+	case importFile != "":
+		events, err = collector.ImportEvents(importFile)
+	case isPipedInput():
+		events, err = collector.ReadEvents(os.Stdin)
+	default:
+		fmt.Fprintln(os.Stderr, "Error: No piped input detected. Usage: go test -json ./... | gotestui report")
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	for _, e := range exports {
+		if err := e.export(e.filename, events); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s report: %v\n", e.name, err)
+			return 2
+		}
+	}
+
+	results := collector.CollectResults(events)
+	if err := collector.WriteSummary(os.Stdout, results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing summary: %v\n", err)
+		return 2
+	}
+	if results.HasFailures() {
+		return 1
+	}
+	return 0
+}
+
+// collectRun runs go test and returns all of its events
+func collectRun(ctx context.Context, args collector.TestArgs) ([]collector.TestEvent, error) {
+	eventChan := make(chan collector.TestEvent, 1000)
+	done := make(chan struct{})
+
+	var events []collector.TestEvent
+	go func() {
+		defer close(done)
+		for te := range eventChan {
+			events = append(events, te)
+		}
+	}()
+
+	err := collector.Run(ctx, args, eventChan)
+	close(eventChan)
+	<-done
+	return events, err
+}
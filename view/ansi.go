@@ -0,0 +1,242 @@
+package view
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// tagPattern matches text tview would read as a style tag, the same expression tview.Escape uses
+var tagPattern = regexp.MustCompile(`\[[a-zA-Z0-9_,;: \-\."#]+\[*\]`)
+
+// ansiColorNames are the tview names of the 16 standard ANSI colors
+var ansiColorNames = [...]string{
+	"black", "maroon", "green", "olive", "navy", "purple", "teal", "silver",
+	"gray", "red", "lime", "yellow", "blue", "fuchsia", "aqua", "white",
+}
+
+// Highlight styles of search matches (vim-like IncSearch and Search)
+const (
+	currentMatchTag = "[#000000:#ff8800:b]"
+	otherMatchTag   = "[#000000:#ffff00:b]"
+	defaultStyleTag = "[-:-:-]"
+)
+
+// ansiStyle is the text style set by ANSI SGR sequences, empty fields being the defaults
+type ansiStyle struct {
+	fg, bg, attrs string
+}
+
+// tag returns the tview style tag of the style
+func (s ansiStyle) tag() string {
+	fg, bg, attrs := s.fg, s.bg, s.attrs
+	if fg == "" {
+		fg = "-"
+	}
+	if bg == "" {
+		bg = "-"
+	}
+	if attrs == "" {
+		attrs = "-"
+	}
+	return "[" + fg + ":" + bg + ":" + attrs + "]"
+}
+
+// setAttr adds or removes a tview attribute flag
+func (s *ansiStyle) setAttr(flag string, on bool) {
+	s.attrs = strings.ReplaceAll(s.attrs, flag, "")
+	if on {
+		s.attrs += flag
+	}
+}
+
+// apply updates the style with the parameters of an SGR sequence
+func (s *ansiStyle) apply(params string) {
+	fields := strings.Split(params, ";")
+	for i := 0; i < len(fields); i++ {
+		n, _ := strconv.Atoi(fields[i]) // An empty parameter means 0
+		switch {
+		case n == 0:
+			*s = ansiStyle{}
+		case n == 1:
+			s.setAttr("b", true)
+		case n == 2:
+			s.setAttr("d", true)
+		case n == 3:
+			s.setAttr("i", true)
+		case n == 4:
+			s.setAttr("u", true)
+		case n == 5:
+			s.setAttr("l", true)
+		case n == 7:
+			s.setAttr("r", true)
+		case n == 9:
+			s.setAttr("s", true)
+		case n == 22:
+			s.setAttr("b", false)
+			s.setAttr("d", false)
+		case n == 23:
+			s.setAttr("i", false)
+		case n == 24:
+			s.setAttr("u", false)
+		case n == 25:
+			s.setAttr("l", false)
+		case n == 27:
+			s.setAttr("r", false)
+		case n == 29:
+			s.setAttr("s", false)
+		case n >= 30 && n <= 37:
+			s.fg = ansiColorNames[n-30]
+		case n == 39:
+			s.fg = ""
+		case n >= 40 && n <= 47:
+			s.bg = ansiColorNames[n-40]
+		case n == 49:
+			s.bg = ""
+		case n >= 90 && n <= 97:
+			s.fg = ansiColorNames[n-90+8]
+		case n >= 100 && n <= 107:
+			s.bg = ansiColorNames[n-100+8]
+		case n == 38 || n == 48:
+			color, consumed := extendedColor(fields[i+1:])
+			i += consumed
+			if color == "" {
+				continue
+			}
+			if n == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor parses the 256-color (5;n) or true color (2;r;g;b) arguments of SGR 38 and 48
+// and returns the color and the number of parameters it consumed
+func extendedColor(fields []string) (string, int) {
+	switch {
+	case len(fields) >= 2 && fields[0] == "5":
+		n, _ := strconv.Atoi(fields[1])
+		switch {
+		case n < 16:
+			return ansiColorNames[n], 2
+		case n < 232:
+			n -= 16
+			return fmt.Sprintf("#%02x%02x%02x", 255*(n/36)/5, 255*(n/6%6)/5, 255*(n%6)/5), 2
+		case n < 256:
+			grey := 255 * (n - 232) / 23
+			return fmt.Sprintf("#%02x%02x%02x", grey, grey, grey), 2
+		}
+		return "", 2
+	case len(fields) >= 4 && fields[0] == "2":
+		r, _ := strconv.Atoi(fields[1])
+		g, _ := strconv.Atoi(fields[2])
+		b, _ := strconv.Atoi(fields[3])
+		return fmt.Sprintf("#%02x%02x%02x", r&0xff, g&0xff, b&0xff), 4
+	}
+	return "", len(fields)
+}
+
+// logSegment is a run of log text shown in a single style
+type logSegment struct {
+	text  string
+	style ansiStyle
+}
+
+// parseANSI splits output into styled segments of plain text, dropping every escape sequence
+func parseANSI(output string) []logSegment {
+	var segments []logSegment
+	var style ansiStyle
+	pos := 0
+	for _, loc := range collector.ANSIPattern.FindAllStringSubmatchIndex(output, -1) {
+		if loc[0] > pos {
+			segments = append(segments, logSegment{text: output[pos:loc[0]], style: style})
+		}
+		pos = loc[1]
+		// Only SGR sequences (final byte 'm') change the style
+		if loc[4] >= 0 && output[loc[4]:loc[5]] == "m" {
+			style.apply(output[loc[2]:loc[3]])
+		}
+	}
+	if pos < len(output) {
+		segments = append(segments, logSegment{text: output[pos:], style: style})
+	}
+	return segments
+}
+
+// plainText returns the text of the segments without styles
+func plainText(segments []logSegment) string {
+	var builder strings.Builder
+	for _, seg := range segments {
+		builder.WriteString(seg.text)
+	}
+	return builder.String()
+}
+
+// renderLog returns the segments as tview text, with literal tags escaped and the matches
+// of re in the plain text highlighted on top of the ANSI styles.
+// currentMatch indicates which match (0-indexed) is highlighted as current (-1 for none).
+func renderLog(segments []logSegment, re *regexp.Regexp, currentMatch int) string {
+	plain := plainText(segments)
+	var matches [][]int
+	if re != nil {
+		matches = matchPositions(plain, re)
+	}
+	// Escape literal tags in the whole text, as the pieces below may split them
+	escapes := tagPattern.FindAllStringIndex(plain, -1)
+
+	var result strings.Builder
+	lastTag := defaultStyleTag
+	offset, matchIndex, escapeIndex, tagOffset := 0, 0, 0, 0
+	for _, seg := range segments {
+		text := seg.text
+		for len(text) > 0 {
+			// Skip matches that ended before this piece
+			for matchIndex < len(matches) && matches[matchIndex][1] <= offset {
+				matchIndex++
+			}
+
+			// Cut the piece at the next match boundary
+			tag, end := seg.style.tag(), len(text)
+			if matchIndex < len(matches) {
+				start, stop := matches[matchIndex][0], matches[matchIndex][1]
+				if start <= offset {
+					tag = otherMatchTag
+					if matchIndex == currentMatch {
+						tag = currentMatchTag
+					}
+					end = min(end, stop-offset)
+				} else {
+					end = min(end, start-offset)
+				}
+			}
+
+			if tag != lastTag {
+				result.WriteString(tag)
+				lastTag, tagOffset = tag, offset
+			}
+			// Write "[" before the closing bracket of every literal tag in the piece,
+			// unless a style tag written inside the literal one already breaks it up
+			piece, pos := text[:end], offset
+			for ; escapeIndex < len(escapes) && escapes[escapeIndex][1]-1 < offset+end; escapeIndex++ {
+				if escapes[escapeIndex][0] < tagOffset {
+					continue
+				}
+				closing := escapes[escapeIndex][1] - 1
+				result.WriteString(piece[:closing-pos] + "[")
+				piece, pos = piece[closing-pos:], closing
+			}
+			result.WriteString(piece)
+			text = text[end:]
+			offset += end
+		}
+	}
+	if lastTag != defaultStyleTag {
+		result.WriteString(defaultStyleTag)
+	}
+	return result.String()
+}
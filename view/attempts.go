@@ -0,0 +1,76 @@
+package view
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// attemptCounts returns the counts of the attempts of a test
+func attemptCounts(attempts [][]collector.TestEvent) statusCounts {
+	var c statusCounts
+	for _, events := range attempts {
+		c.add(leafCounts(events))
+	}
+	return c
+}
+
+// updateAttempts adds a child node per attempt to a test without subtests run more than once
+// and updates the attempts that may have changed, i.e. the running and the new ones.
+// Tests with subtests show the attempts of their subtests instead.
+func (h *History) updateAttempts(node *tview.TreeNode, key collector.TestKey, events []collector.TestEvent, spinnerIcon string) {
+	attempts := collector.SplitAttempts(events)
+	if len(attempts) < 2 || h.hasSubtests(node) {
+		return
+	}
+
+	var nodes []*tview.TreeNode
+	for _, child := range h.children[node] {
+		if isAttemptNode(child) {
+			nodes = append(nodes, child)
+		}
+	}
+	known := len(nodes)
+	for len(nodes) < len(attempts) {
+		child := tview.NewTreeNode("")
+		h.addChild(node, child)
+		nodes = append(nodes, child)
+	}
+	for i, events := range attempts {
+		// The processes of a stress run may still add to earlier attempts
+		if i < known {
+			ref := nodes[i].GetReference().(*nodeRef)
+			if len(ref.events) == len(events) && lastTerminalAction(events) != "" {
+				continue
+			}
+		}
+		nodes[i].SetReference(&nodeRef{key: key, events: events, attempt: i + 1})
+		renderNode(h, nodes[i], spinnerIcon)
+	}
+}
+
+// isAttemptNode reports whether a node shows a single attempt of a test
+func isAttemptNode(node *tview.TreeNode) bool {
+	ref, ok := node.GetReference().(*nodeRef)
+	return ok && ref.attempt > 0
+}
+
+// hasSubtests reports whether a test node has subtest nodes, besides its attempts
+func (h *History) hasSubtests(node *tview.TreeNode) bool {
+	for _, child := range h.children[node] {
+		if !isAttemptNode(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatAttemptText formats the display text of an attempt node, like "#2 ✗ [0.013s]"
+func formatAttemptText(attempt int, statusIcon string, elapsed float64) string {
+	text := fmt.Sprintf("#%d %s", attempt, statusIcon)
+	if elapsed > 0 {
+		text += fmt.Sprintf(" [%.3fs]", elapsed)
+	}
+	return text
+}
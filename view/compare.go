@@ -0,0 +1,236 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+const (
+	// slowdownRatio is how much slower a test must get to be reported as a duration regression
+	slowdownRatio = 1.2
+	// slowdownMinimum is the minimum increase in seconds reported as a duration regression,
+	// ignoring the noise of fast tests
+	slowdownMinimum = 0.1
+)
+
+// changeKind classifies how a test differs between two histories
+type changeKind int
+
+const (
+	changeBroken  changeKind = iota // Passed, then failed
+	changeFixed                     // Failed, then passed
+	changeSkipped                   // Newly skipped
+	changeAdded                     // Only in the newer history
+	changeRemoved                   // Only in the older history
+	changeSlower                    // Duration regression
+)
+
+var changeKindNames = [...]string{"pass → fail", "fail → pass", "newly skipped", "new tests", "removed tests", "slower"}
+
+// String returns the section title of the change kind
+func (k changeKind) String() string {
+	return changeKindNames[k]
+}
+
+// testChange is a difference of a test between two histories
+type testChange struct {
+	kind          changeKind
+	key           collector.TestKey
+	before, after collector.Action // Last terminal actions
+	elapsedBefore float64
+	elapsedAfter  float64
+}
+
+// testOutcome is the result of a test in a history
+type testOutcome struct {
+	action  collector.Action
+	elapsed float64
+	leaf    bool // Without subtests
+}
+
+// historyOutcomes returns the results of the tests of a history and the packages it ran
+func historyOutcomes(h *History) (map[collector.TestKey]testOutcome, map[string]bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	parents := make(map[collector.TestKey]bool)
+	packages := make(map[string]bool)
+	for key := range h.TestCases {
+		packages[key.Package] = true
+		for i := strings.LastIndex(key.Test, "/"); i >= 0; i = strings.LastIndex(key.Test[:i], "/") {
+			parents[collector.TestKey{Package: key.Package, Test: key.Test[:i]}] = true
+		}
+	}
+
+	outcomes := make(map[collector.TestKey]testOutcome)
+	for key, events := range h.TestCases {
+		if key.Test == "" {
+			continue
+		}
+		outcome := testOutcome{leaf: !parents[key]}
+		for _, te := range events {
+			if te.Action.IsTerminal() {
+				outcome.action, outcome.elapsed = te.Action, te.Elapsed
+			}
+		}
+		outcomes[key] = outcome
+	}
+	return outcomes, packages
+}
+
+// ranAllTests reports whether the history ran every test of a package, without a -run or -skip
+// filter of its own or of a rerun of selected tests
+func (h *History) ranAllTests(pkg string) bool {
+	if h.partial[pkg] {
+		return false
+	}
+	if h.Args != nil {
+		if _, ok := h.Args.FlagValue("run"); ok {
+			return false
+		}
+		if _, ok := h.Args.FlagValue("skip"); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compareHistories returns the status changes, added and removed tests and duration regressions
+// of the tests without subtests from base to target, by kind then in package and test order.
+// Tests are only reported as added or removed when the history missing them ran all the tests
+// of their package.
+func compareHistories(base, target *History) []testChange {
+	before, basePackages := historyOutcomes(base)
+	after, targetPackages := historyOutcomes(target)
+
+	var keys []collector.TestKey
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sortTestKeys(keys)
+
+	var changes []testChange
+	for _, key := range keys {
+		b, inBase := before[key]
+		a, inTarget := after[key]
+		if !a.leaf && !b.leaf {
+			continue
+		}
+		change := testChange{key: key, before: b.action, after: a.action, elapsedBefore: b.elapsed, elapsedAfter: a.elapsed}
+		switch {
+		case !inBase:
+			if !basePackages[key.Package] || !base.ranAllTests(key.Package) {
+				continue
+			}
+			change.kind = changeAdded
+		case !inTarget:
+			if !targetPackages[key.Package] || !target.ranAllTests(key.Package) {
+				continue
+			}
+			change.kind = changeRemoved
+		case b.action == collector.ActionPass && a.action == collector.ActionFail:
+			change.kind = changeBroken
+		case b.action == collector.ActionFail && a.action == collector.ActionPass:
+			change.kind = changeFixed
+		case b.action != collector.ActionSkip && a.action == collector.ActionSkip:
+			change.kind = changeSkipped
+		case b.action == collector.ActionPass && a.action == collector.ActionPass &&
+			a.elapsed > b.elapsed*slowdownRatio && a.elapsed-b.elapsed >= slowdownMinimum:
+			change.kind = changeSlower
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	// Group by kind, keeping the key order within each kind
+	var grouped []testChange
+	for kind := changeBroken; kind <= changeSlower; kind++ {
+		for _, c := range changes {
+			if c.kind == kind {
+				grouped = append(grouped, c)
+			}
+		}
+	}
+	return grouped
+}
+
+// changeText formats a change for the comparison list
+func changeText(c testChange) string {
+	name := lastPathComponent(c.key.Package) + " " + c.key.Test
+	switch c.kind {
+	case changeSlower:
+		return fmt.Sprintf("  %s %.3fs → %.3fs (+%.0f%%)", name, c.elapsedBefore, c.elapsedAfter,
+			100*(c.elapsedAfter-c.elapsedBefore)/max(c.elapsedBefore, 0.001))
+	case changeAdded, changeRemoved:
+		action := c.after
+		if c.kind == changeRemoved {
+			action = c.before
+		}
+		if action == "" {
+			return "  " + name
+		}
+		return fmt.Sprintf("  %s (%s)", name, action)
+	}
+	return "  " + name
+}
+
+// newCompareList creates the list of changes from base to target, under a heading per kind.
+// selected is called with the chosen change and done when the list is closed.
+func newCompareList(base, target *History, selected func(testChange), done func()) *tview.List {
+	changes := compareHistories(base, target)
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true)
+	list.SetTitle(tview.Escape(fmt.Sprintf("Compare %s → %s (%d changes)", base.Name, target.Name, len(changes))))
+	if len(changes) == 0 {
+		list.AddItem("No changes", "", 0, nil)
+	}
+
+	// Headings map to no change
+	items := make([]*testChange, 0, len(changes))
+	for i, c := range changes {
+		if i == 0 || changes[i-1].kind != c.kind {
+			count := 0
+			for _, other := range changes {
+				if other.kind == c.kind {
+					count++
+				}
+			}
+			list.AddItem(fmt.Sprintf("[yellow::b]%s (%d)", c.kind, count), "", 0, nil)
+			items = append(items, nil)
+		}
+		list.AddItem(tview.Escape(changeText(c)), "", 0, nil)
+		items = append(items, &changes[i])
+	}
+
+	list.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		if index < len(items) && items[index] != nil {
+			selected(*items[index])
+		}
+	})
+	list.SetDoneFunc(done)
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune {
+			switch event.Rune() {
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			case 'd':
+				done()
+				return nil
+			}
+		}
+		return event
+	})
+	return list
+}
@@ -0,0 +1,101 @@
+package view
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// statusCounts holds the number of leaf tests below a node by status
+type statusCounts struct {
+	passed, failed, skipped, running int
+}
+
+// total returns the number of counted tests
+func (c statusCounts) total() int {
+	return c.passed + c.failed + c.skipped + c.running
+}
+
+// add adds the counts of o to c
+func (c *statusCounts) add(o statusCounts) {
+	c.passed += o.passed
+	c.failed += o.failed
+	c.skipped += o.skipped
+	c.running += o.running
+}
+
+// String formats the counts as failed/total, finished/total while tests are running,
+// or the total, followed by the number of skipped tests
+func (c statusCounts) String() string {
+	var text string
+	switch {
+	case c.failed > 0:
+		text = fmt.Sprintf("%d/%d", c.failed, c.total())
+	case c.running > 0:
+		text = fmt.Sprintf("%d/%d", c.total()-c.running, c.total())
+	default:
+		text = strconv.Itoa(c.total())
+	}
+	if c.skipped > 0 {
+		text += fmt.Sprintf(" ⏭%d", c.skipped)
+	}
+	return text
+}
+
+// color returns the node color derived from the counts, falling back to the node's own color
+func (c statusCounts) color(own tcell.Color) tcell.Color {
+	switch {
+	case c.failed > 0:
+		return tcell.ColorRed
+	case c.running > 0:
+		return tcell.ColorYellow
+	}
+	return own
+}
+
+// leafCounts returns the counts of a single test from its events. A test repeated with -count
+// is running during its last attempt and failed when any attempt failed.
+func leafCounts(events []collector.TestEvent) statusCounts {
+	var c statusCounts
+	action := lastTerminalAction(events)
+	if attempts := collector.SplitAttempts(events); len(attempts) > 1 {
+		switch {
+		case lastTerminalAction(attempts[len(attempts)-1]) == "":
+			action = ""
+		case attemptCounts(attempts).failed > 0:
+			action = collector.ActionFail
+		}
+	}
+	switch action {
+	case collector.ActionPass, collector.ActionBench:
+		c.passed = 1
+	case collector.ActionFail:
+		c.failed = 1
+	case collector.ActionSkip:
+		c.skipped = 1
+	default:
+		c.running = 1
+	}
+	return c
+}
+
+// updateCounts recomputes the counts of a node from its children.
+// Tests with subtests only contribute their subtests, like the summary of collector.CollectResults,
+// and the attempts of repeated tests are counted through their test.
+func (h *History) updateCounts(node *tview.TreeNode) {
+	var c statusCounts
+	for _, child := range h.children[node] {
+		ref, ok := child.GetReference().(*nodeRef)
+		switch {
+		case ok && ref.attempt > 0:
+		case h.hasSubtests(child):
+			c.add(h.counts[child])
+		case ok:
+			c.add(leafCounts(ref.events))
+		}
+	}
+	h.counts[node] = c
+}
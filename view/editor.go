@@ -0,0 +1,57 @@
+package view
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// locationPattern matches the file:line locations of Go files printed by t.Log, t.Error
+// and in the stack traces of panics
+var locationPattern = regexp.MustCompile(`[^\s:"'()\[\]]+\.go:\d+`)
+
+// splitLocation returns the file and the line of a location matched by locationPattern
+func splitLocation(loc string) (string, int) {
+	idx := strings.LastIndex(loc, ":")
+	line, _ := strconv.Atoi(loc[idx+1:])
+	return loc[:idx], line
+}
+
+// resolveLocation returns the path of a file printed in the log of a package.
+// Absolute paths are kept, other paths are looked up in the package directory first
+// and then in the working directory.
+func resolveLocation(file, pkgDir string) (string, error) {
+	if filepath.IsAbs(file) {
+		return file, nil
+	}
+	candidates := []string{file}
+	if pkgDir != "" {
+		candidates = append([]string{filepath.Join(pkgDir, file)}, candidates...)
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("file not found: %s", file)
+}
+
+// editorCommand returns the command opening file at line in $VISUAL or $EDITOR, or in vi
+func editorCommand(file string, line int) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	args = append(args, fmt.Sprintf("+%d", line), file)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd
+}
@@ -0,0 +1,152 @@
+package view
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// StatusFilter selects the tests shown in the tree by their status
+type StatusFilter int
+
+const (
+	FilterAll     StatusFilter = iota // Every test
+	FilterFailed                      // Failed tests only
+	FilterSkipped                     // Skipped tests only
+	FilterRunning                     // Running tests only
+)
+
+var statusFilterNames = [...]string{"all", "failed", "skipped", "running"}
+
+// String returns the name of the status filter
+func (s StatusFilter) String() string {
+	return statusFilterNames[s]
+}
+
+// Next returns the status filter that follows s when cycling through them
+func (s StatusFilter) Next() StatusFilter {
+	return (s + 1) % StatusFilter(len(statusFilterNames))
+}
+
+// TreeFilter hides the tree nodes that match neither the status nor the name filter.
+// Ancestors of visible nodes always stay visible.
+type TreeFilter struct {
+	Status StatusFilter
+	Name   string
+	Regex  bool
+
+	re        *regexp.Regexp
+	reErr     error
+	lowerName string
+}
+
+// SetName sets the name filter, a case-insensitive substring or a regular expression
+func (f *TreeFilter) SetName(name string, regex bool) {
+	f.Name, f.Regex = name, regex
+	f.lowerName = strings.ToLower(name)
+	f.re, f.reErr = nil, nil
+	if regex && name != "" {
+		f.re, f.reErr = regexp.Compile(name)
+	}
+}
+
+// Active reports whether the filter hides anything
+func (f *TreeFilter) Active() bool {
+	return f.Status != FilterAll || f.Name != ""
+}
+
+// Title returns the tree title describing the filter
+func (f *TreeFilter) Title() string {
+	var parts []string
+	if f.Status != FilterAll {
+		parts = append(parts, f.Status.String())
+	}
+	switch {
+	case f.Name == "":
+	case f.reErr != nil:
+		parts = append(parts, "invalid regex: "+f.Name)
+	case f.Regex:
+		parts = append(parts, "regex: "+f.Name)
+	default:
+		parts = append(parts, "name: "+f.Name)
+	}
+	if len(parts) == 0 {
+		return "Tests"
+	}
+	return fmt.Sprintf("Tests (%s)", strings.Join(parts, ", "))
+}
+
+// matchesName reports whether the full or last element of the test name, or the import path
+// of a package node, matches
+func (f *TreeFilter) matchesName(ref *nodeRef) bool {
+	if f.Name == "" || f.reErr != nil {
+		return true
+	}
+	if ref == nil {
+		return false
+	}
+	name := ref.key.Test
+	if name == "" {
+		name = ref.key.Package
+	}
+	if f.re != nil {
+		return f.re.MatchString(name) || f.re.MatchString(lastPathComponent(name))
+	}
+	return strings.Contains(strings.ToLower(name), f.lowerName)
+}
+
+// matchesStatus reports whether the status of the events matches, counting the attempts of a
+// test repeated with -count the same way as the tree
+func (f *TreeFilter) matchesStatus(ref *nodeRef) bool {
+	if f.Status == FilterAll {
+		return true
+	}
+	if ref == nil {
+		return false
+	}
+	switch f.Status {
+	case FilterFailed:
+		return leafCounts(ref.events).failed > 0
+	case FilterSkipped:
+		return leafCounts(ref.events).skipped > 0
+	case FilterRunning:
+		return isTestRunning(ref.events)
+	}
+	return false
+}
+
+// lastTerminalAction returns the last pass, fail, skip or bench action of the events
+func lastTerminalAction(events []collector.TestEvent) collector.Action {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Action.IsTerminal() {
+			return events[i].Action
+		}
+	}
+	return ""
+}
+
+// applyFilter shows the nodes of the history that pass the filter, restoring the full tree
+// when the filter is inactive
+func applyFilter(h *History, f *TreeFilter) {
+	filterNode(h.Root, h.children, f, false)
+}
+
+// filterNode sets the visible children of node and reports whether node itself stays visible.
+// nameMatched is true when an ancestor matched the name filter, which then applies to the whole subtree.
+func filterNode(node *tview.TreeNode, children map[*tview.TreeNode][]*tview.TreeNode, f *TreeFilter, nameMatched bool) bool {
+	ref, _ := node.GetReference().(*nodeRef)
+	nameOK := nameMatched || f.matchesName(ref)
+
+	var visible []*tview.TreeNode
+	for _, child := range children[node] {
+		if filterNode(child, children, f, nameOK) {
+			visible = append(visible, child)
+		}
+	}
+	node.SetChildren(visible)
+	updateNodeExpandIcon(node)
+	return len(visible) > 0 || (nameOK && f.matchesStatus(ref))
+}
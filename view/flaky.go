@@ -0,0 +1,144 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// flakyIcon marks flaky tests in the tree
+const flakyIcon = "❄"
+
+// flakyTest is a test that both passed and failed, in different histories or in the
+// runs of a single -count=N history
+type flakyTest struct {
+	key         collector.TestKey
+	passes      int
+	failures    int
+	histories   []string // Names of the histories running the test
+	lastFailure *History // Latest history in which the test failed
+}
+
+// findFlakyTests returns the flaky tests of the histories in package and test order.
+// Parent tests only failing through a flaky subtest are left out.
+func findFlakyTests(histories []*History) []flakyTest {
+	byKey := make(map[collector.TestKey]*flakyTest)
+	var keys []collector.TestKey
+	for _, h := range histories {
+		h.mu.Lock()
+		for key, events := range h.TestCases {
+			if key.Test == "" {
+				continue
+			}
+			passes, failures := 0, 0
+			for _, te := range events {
+				switch te.Action {
+				case collector.ActionPass:
+					passes++
+				case collector.ActionFail:
+					failures++
+				}
+			}
+			if passes+failures == 0 {
+				continue
+			}
+			f, ok := byKey[key]
+			if !ok {
+				f = &flakyTest{key: key}
+				byKey[key] = f
+				keys = append(keys, key)
+			}
+			f.passes += passes
+			f.failures += failures
+			f.histories = append(f.histories, h.Name)
+			if failures > 0 {
+				f.lastFailure = h
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	flaky := make(map[collector.TestKey]bool)
+	for key, f := range byKey {
+		if f.passes > 0 && f.failures > 0 {
+			flaky[key] = true
+		}
+	}
+	hasFlakySubtest := make(map[collector.TestKey]bool)
+	for key := range flaky {
+		for i := strings.LastIndex(key.Test, "/"); i >= 0; i = strings.LastIndex(key.Test[:i], "/") {
+			hasFlakySubtest[collector.TestKey{Package: key.Package, Test: key.Test[:i]}] = true
+		}
+	}
+
+	sortTestKeys(keys)
+	var result []flakyTest
+	for _, key := range keys {
+		if flaky[key] && !hasFlakySubtest[key] {
+			result = append(result, *byKey[key])
+		}
+	}
+	return result
+}
+
+// markFlaky flags the flaky tests in the trees of the histories and re-renders the nodes
+// whose flag changed
+func markFlaky(histories []*History, flaky []flakyTest, spinnerIcon string) {
+	keys := make(map[collector.TestKey]bool)
+	for _, f := range flaky {
+		keys[f.key] = true
+	}
+	for _, h := range histories {
+		for _, node := range h.NodeMap {
+			ref, ok := node.GetReference().(*nodeRef)
+			if !ok || h.flaky[ref.key] == keys[ref.key] {
+				continue
+			}
+			if keys[ref.key] {
+				h.flaky[ref.key] = true
+			} else {
+				delete(h.flaky, ref.key)
+			}
+			renderNode(h, node, spinnerIcon)
+		}
+	}
+}
+
+// newFlakyList creates the list of flaky tests. selected is called with the chosen test
+// and done when the list is closed.
+func newFlakyList(flaky []flakyTest, selected func(flakyTest), done func()) *tview.List {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true)
+	if len(flaky) == 0 {
+		list.SetTitle("Flaky tests (none)")
+	} else {
+		list.SetTitle(fmt.Sprintf("Flaky tests (%d)", len(flaky)))
+	}
+	for _, f := range flaky {
+		text := fmt.Sprintf("%s %s %s ✓%d ✗%d in %s", flakyIcon, lastPathComponent(f.key.Package), f.key.Test,
+			f.passes, f.failures, strings.Join(f.histories, ", "))
+		list.AddItem(tview.Escape(text), "", 0, nil)
+	}
+	list.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		selected(flaky[index])
+	})
+	list.SetDoneFunc(done)
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune {
+			switch event.Rune() {
+			case 'j':
+				return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+			case 'k':
+				return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+			case 'y':
+				done()
+				return nil
+			}
+		}
+		return event
+	})
+	return list
+}
@@ -0,0 +1,285 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// maxDiffCells bounds the size of the LCS table; larger logs are shown as entirely changed
+// apart from their common prefix and suffix
+const maxDiffCells = 16 << 20
+
+// diffOp is the kind of a line of a diff
+type diffOp int
+
+const (
+	diffEqual  diffOp = iota // In both logs
+	diffDelete               // Only in the left log
+	diffInsert               // Only in the right log
+)
+
+// diffLine is a line of a diff
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// diffLines returns the line diff of a and b, based on their longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var result []diffLine
+	for _, line := range a[:prefix] {
+		result = append(result, diffLine{diffEqual, line})
+	}
+	result = append(result, lcsDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, diffLine{diffEqual, line})
+	}
+	return result
+}
+
+// lcsDiff returns the line diff of a and b using a longest common subsequence table
+func lcsDiff(a, b []string) []diffLine {
+	var result []diffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			result = append(result, diffLine{diffDelete, line})
+		}
+		for _, line := range b {
+			result = append(result, diffLine{diffInsert, line})
+		}
+		return result
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int32, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, diffLine{diffEqual, a[i]})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			result = append(result, diffLine{diffDelete, a[i]})
+			i++
+		default:
+			result = append(result, diffLine{diffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result = append(result, diffLine{diffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		result = append(result, diffLine{diffInsert, b[j]})
+	}
+	return result
+}
+
+// logLines splits a log into lines without ANSI escape sequences
+func logLines(output string) []string {
+	text := strings.TrimSuffix(plainText(parseANSI(output)), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// renderUnifiedDiff returns the diff as tview text with -/+ prefixes and the rows where
+// runs of changes start
+func renderUnifiedDiff(diff []diffLine) (string, []int) {
+	var builder strings.Builder
+	var changes []int
+	for row, line := range diff {
+		if line.op != diffEqual && (row == 0 || diff[row-1].op == diffEqual) {
+			changes = append(changes, row)
+		}
+		switch line.op {
+		case diffEqual:
+			builder.WriteString("  " + tview.Escape(line.text))
+		case diffDelete:
+			builder.WriteString("[red]- " + tview.Escape(line.text) + "[-]")
+		case diffInsert:
+			builder.WriteString("[green]+ " + tview.Escape(line.text) + "[-]")
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String(), changes
+}
+
+// renderSideBySideDiff returns the left and right columns of the diff as tview text with aligned
+// rows, pairing the deleted and inserted lines of each run of changes, and the rows where
+// the runs start
+func renderSideBySideDiff(diff []diffLine) (string, string, []int) {
+	var left, right strings.Builder
+	var changes []int
+	row := 0
+	for i := 0; i < len(diff); {
+		if diff[i].op == diffEqual {
+			left.WriteString(tview.Escape(diff[i].text) + "\n")
+			right.WriteString(tview.Escape(diff[i].text) + "\n")
+			i++
+			row++
+			continue
+		}
+
+		var deleted, inserted []string
+		for ; i < len(diff) && diff[i].op != diffEqual; i++ {
+			if diff[i].op == diffDelete {
+				deleted = append(deleted, diff[i].text)
+			} else {
+				inserted = append(inserted, diff[i].text)
+			}
+		}
+		changes = append(changes, row)
+		for k := 0; k < max(len(deleted), len(inserted)); k++ {
+			if k < len(deleted) {
+				left.WriteString("[red]" + tview.Escape(deleted[k]) + "[-]")
+			}
+			if k < len(inserted) {
+				right.WriteString("[green]" + tview.Escape(inserted[k]) + "[-]")
+			}
+			left.WriteString("\n")
+			right.WriteString("\n")
+			row++
+		}
+	}
+	return left.String(), right.String(), changes
+}
+
+// logDiffPanel shows the logs of a test in two histories side by side or as a unified diff
+type logDiffPanel struct {
+	*tview.Flex
+	left, right *tview.TextView
+	unified     *tview.TextView
+	diff        []diffLine
+	changes     []int
+	change      int
+	isUnified   bool
+	title       string
+}
+
+// newLogDiffPanel creates the diff of the logs of a test, the left one being the older.
+// done is called when the panel is closed with Esc or D.
+func newLogDiffPanel(app *tview.Application, test, leftName, leftLog, rightName, rightLog string, done func()) *logDiffPanel {
+	newView := func() *tview.TextView {
+		view := tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+		view.SetBorder(true)
+		return view
+	}
+	p := &logDiffPanel{
+		Flex:    tview.NewFlex(),
+		left:    newView(),
+		right:   newView(),
+		unified: newView(),
+		diff:    diffLines(logLines(leftLog), logLines(rightLog)),
+		title:   test,
+	}
+	p.SetBorder(true)
+	p.left.SetTitle(tview.Escape(leftName))
+	p.right.SetTitle(tview.Escape(rightName))
+	p.unified.SetTitle(tview.Escape(fmt.Sprintf("%s → %s", leftName, rightName)))
+	p.render()
+
+	p.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, col := p.scrollOffset()
+		switch {
+		case event.Key() == tcell.KeyEsc || event.Rune() == 'D':
+			done()
+		case event.Key() == tcell.KeyDown || event.Rune() == 'j':
+			p.scrollTo(row+1, col)
+		case event.Key() == tcell.KeyUp || event.Rune() == 'k':
+			p.scrollTo(max(row-1, 0), col)
+		case event.Key() == tcell.KeyRight || event.Rune() == 'l':
+			p.scrollTo(row, col+8)
+		case event.Key() == tcell.KeyLeft || event.Rune() == 'h':
+			p.scrollTo(row, max(col-8, 0))
+		case event.Rune() == 'g':
+			p.scrollTo(0, 0)
+		case event.Rune() == 'G':
+			p.scrollTo(len(p.diff), 0)
+		case event.Rune() == 'n':
+			p.jumpToChange(p.change + 1)
+		case event.Rune() == 'N':
+			p.jumpToChange(p.change - 1)
+		case event.Rune() == 'u':
+			p.isUnified = !p.isUnified
+			p.render()
+			app.SetFocus(p)
+		default:
+			return event
+		}
+		return nil
+	})
+	return p
+}
+
+// render lays out the diff in the current mode
+func (p *logDiffPanel) render() {
+	p.Clear()
+	if p.isUnified {
+		var text string
+		text, p.changes = renderUnifiedDiff(p.diff)
+		p.unified.SetText(text)
+		p.AddItem(p.unified, 0, 1, true)
+	} else {
+		var left, right string
+		left, right, p.changes = renderSideBySideDiff(p.diff)
+		p.left.SetText(left)
+		p.right.SetText(right)
+		p.AddItem(p.left, 0, 1, true).
+			AddItem(p.right, 0, 1, false)
+	}
+	p.jumpToChange(0)
+}
+
+// scrollOffset returns the scroll position of the visible diff
+func (p *logDiffPanel) scrollOffset() (int, int) {
+	if p.isUnified {
+		return p.unified.GetScrollOffset()
+	}
+	return p.left.GetScrollOffset()
+}
+
+// scrollTo scrolls every column of the diff together
+func (p *logDiffPanel) scrollTo(row, col int) {
+	p.left.ScrollTo(row, col)
+	p.right.ScrollTo(row, col)
+	p.unified.ScrollTo(row, col)
+}
+
+// jumpToChange scrolls to a run of changes, wrapping around, and updates the titles
+func (p *logDiffPanel) jumpToChange(index int) {
+	status := "no differences"
+	if len(p.changes) > 0 {
+		p.change = (index + len(p.changes)) % len(p.changes)
+		p.scrollTo(p.changes[p.change], 0)
+		status = fmt.Sprintf("change %d/%d", p.change+1, len(p.changes))
+	}
+	p.SetTitle(tview.Escape(fmt.Sprintf("Log diff: %s (%s)", p.title, status)))
+}
@@ -0,0 +1,121 @@
+package view
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// caseMode selects how letter case is handled by log searches
+type caseMode int
+
+const (
+	caseIgnore caseMode = iota // Case-insensitive
+	caseSmart                  // Case-sensitive only when the query has upper case letters
+	caseMatch                  // Case-sensitive
+)
+
+var caseModeNames = [...]string{"ignore case", "smart case", "match case"}
+
+// searchOptions holds the log search modes
+type searchOptions struct {
+	regex    bool
+	caseMode caseMode
+}
+
+// toggleRegex switches between substring and regular expression search
+func (o *searchOptions) toggleRegex() {
+	o.regex = !o.regex
+}
+
+// nextCaseMode cycles through ignore case, smart case and match case
+func (o *searchOptions) nextCaseMode() {
+	o.caseMode = (o.caseMode + 1) % caseMode(len(caseModeNames))
+}
+
+// compile returns the expression matching query in the selected modes
+func (o searchOptions) compile(query string) (*regexp.Regexp, error) {
+	expr := query
+	if !o.regex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if o.caseMode == caseIgnore || (o.caseMode == caseSmart && !hasUpperLiteral(query, o.regex)) {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// hasUpperLiteral reports whether the query searches for an upper case letter. In a regular
+// expression, escapes such as \S or \p{Lu} and group flags and names are not literals.
+func hasUpperLiteral(query string, regex bool) bool {
+	if !regex {
+		return strings.ToLower(query) != query
+	}
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes):
+			i++
+			// Skip the name of \p{Name} or \pN
+			if (runes[i] == 'p' || runes[i] == 'P') && i+1 < len(runes) {
+				i++
+				if runes[i] == '{' {
+					for i < len(runes) && runes[i] != '}' {
+						i++
+					}
+				}
+			}
+		case runes[i] == '(' && i+1 < len(runes) && runes[i+1] == '?':
+			// Skip flags and group names up to the start of the group
+			for i < len(runes) && runes[i] != ':' && runes[i] != ')' && runes[i] != '>' {
+				i++
+			}
+		case unicode.IsUpper(runes[i]):
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the modes that differ from the default case-insensitive substring search
+func (o searchOptions) String() string {
+	var modes []string
+	if o.regex {
+		modes = append(modes, "regex")
+	}
+	if o.caseMode != caseIgnore {
+		modes = append(modes, caseModeNames[o.caseMode])
+	}
+	return strings.Join(modes, ", ")
+}
+
+// label returns the search input label, showing the active modes
+func (o searchOptions) label() string {
+	if modes := o.String(); modes != "" {
+		return "(" + modes + ") /"
+	}
+	return "/"
+}
+
+// matchPositions returns the start and end of every non-empty match of re in text
+func matchPositions(text string, re *regexp.Regexp) [][]int {
+	var positions [][]int
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if loc[1] > loc[0] {
+			positions = append(positions, loc)
+		}
+	}
+	return positions
+}
+
+// matchLines returns the line number of every non-empty match of re in text
+func matchLines(text string, re *regexp.Regexp) []int {
+	var lines []int
+	line, pos := 0, 0
+	for _, loc := range matchPositions(text, re) {
+		line += strings.Count(text[pos:loc[0]], "\n")
+		pos = loc[0]
+		lines = append(lines, line)
+	}
+	return lines
+}
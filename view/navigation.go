@@ -0,0 +1,70 @@
+package view
+
+import "github.com/rivo/tview"
+
+// findFailure returns the path from the root to the next failed node after current in depth-first
+// order, or to the previous one when backward is set, wrapping around at the ends.
+// A nil current starts from the top. It returns nil when the tree has no failures.
+func findFailure(root, current *tview.TreeNode, backward bool) []*tview.TreeNode {
+	var paths [][]*tview.TreeNode
+	pos := -1
+	var walk func(node *tview.TreeNode, path []*tview.TreeNode)
+	walk = func(node *tview.TreeNode, path []*tview.TreeNode) {
+		path = append(path[:len(path):len(path)], node)
+		if node == current {
+			pos = len(paths)
+		}
+		paths = append(paths, path)
+		for _, child := range node.GetChildren() {
+			walk(child, path)
+		}
+	}
+	walk(root, nil)
+
+	n := len(paths)
+	if pos < 0 && backward {
+		pos = n
+	}
+	for i := 1; i <= n; i++ {
+		idx := pos + i
+		if backward {
+			idx = pos - i
+		}
+		idx = (idx%n + n) % n
+		if path := paths[idx]; isFailureNode(path[len(path)-1]) {
+			return path
+		}
+	}
+	return nil
+}
+
+// isFailureNode reports whether a node failed on its own account, i.e. it failed
+// and none of its subtests did
+func isFailureNode(node *tview.TreeNode) bool {
+	if !nodeFailed(node) {
+		return false
+	}
+	for _, child := range node.GetChildren() {
+		if nodeFailed(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeFailed reports whether a node failed, in any attempt for a test repeated with -count
+func nodeFailed(node *tview.TreeNode) bool {
+	ref, ok := node.GetReference().(*nodeRef)
+	return ok && leafCounts(ref.events).failed > 0
+}
+
+// selectPath expands the ancestors of the last node of path and moves the tree cursor to it
+func selectPath(treeView *tview.TreeView, path []*tview.TreeNode) {
+	for _, node := range path[:len(path)-1] {
+		if !node.IsExpanded() {
+			node.SetExpanded(true)
+			updateNodeExpandIcon(node)
+		}
+	}
+	treeView.SetCurrentNode(path[len(path)-1])
+}
@@ -0,0 +1,91 @@
+package view
+
+import (
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// rerunBoolFlags lists the boolean go test flags editable in the rerun dialog
+var rerunBoolFlags = []struct{ name, label string }{
+	{"race", "Race (-race)"},
+	{"v", "Verbose (-v)"},
+}
+
+// rerunValueFlags lists the go test flags with a value editable in the rerun dialog
+var rerunValueFlags = []struct{ name, label string }{
+	{"count", "Count (-count)"},
+	{"timeout", "Timeout (-timeout)"},
+	{"tags", "Tags (-tags)"},
+	{"shuffle", "Shuffle (-shuffle)"},
+	{"cpu", "CPU (-cpu)"},
+}
+
+// newRerunForm builds a form to edit the flags and environment of a rerun.
+// done is called with the edited arguments when the user confirms, cancel otherwise.
+func newRerunForm(target string, args collector.TestArgs, done func(collector.TestArgs), cancel func()) *tview.Form {
+	// Flags without a dedicated field are edited as free text
+	other := args.Clone()
+	for _, f := range rerunBoolFlags {
+		other.SetFlag(f.name, "")
+	}
+	for _, f := range rerunValueFlags {
+		other.SetFlag(f.name, "")
+	}
+
+	form := tview.NewForm()
+	for _, f := range rerunBoolFlags {
+		value, _ := args.FlagValue(f.name)
+		form.AddCheckbox(f.label, value == "true", nil)
+	}
+	for _, f := range rerunValueFlags {
+		value, _ := args.FlagValue(f.name)
+		form.AddInputField(f.label, value, 20, nil, nil)
+	}
+	// Values with spaces are quoted like on a shell command line
+	form.AddInputField("Other flags", collector.JoinWords(other.Flags), 0, nil, nil)
+	form.AddInputField("Env (KEY=VALUE ...)", collector.JoinWords(args.Env), 0, nil, nil)
+
+	form.AddButton("Run", func() {
+		flags, err := collector.SplitWords(form.GetFormItemByLabel("Other flags").(*tview.InputField).GetText())
+		if err != nil {
+			form.SetTitle("Rerun " + target + ": other flags: " + err.Error())
+			return
+		}
+		env, err := collector.SplitWords(form.GetFormItemByLabel("Env (KEY=VALUE ...)").(*tview.InputField).GetText())
+		if err != nil {
+			form.SetTitle("Rerun " + target + ": env: " + err.Error())
+			return
+		}
+
+		edited := args.Clone()
+		edited.Flags = flags
+		for _, f := range rerunBoolFlags {
+			if form.GetFormItemByLabel(f.label).(*tview.Checkbox).IsChecked() {
+				edited.SetFlag(f.name, "true")
+			}
+		}
+		for _, f := range rerunValueFlags {
+			edited.SetFlag(f.name, strings.TrimSpace(form.GetFormItemByLabel(f.label).(*tview.InputField).GetText()))
+		}
+		edited.Env = env
+		done(edited)
+	})
+	form.AddButton("Cancel", cancel)
+	form.SetCancelFunc(cancel)
+
+	form.SetBorder(true).SetTitle("Rerun " + target)
+	return form
+}
+
+// modal centers p in a box of the given size on top of the other pages
+func modal(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 0, true).
+			AddItem(nil, 0, 1, false), width, 0, true).
+		AddItem(nil, 0, 1, false)
+}
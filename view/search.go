@@ -0,0 +1,166 @@
+package view
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// searchResult is a test or package whose log matches a global search
+type searchResult struct {
+	history *History
+	key     collector.TestKey
+	hits    int
+}
+
+// searchHistories returns the tests and packages of the histories whose output matches re,
+// in history, package and test order
+func searchHistories(histories []*History, re *regexp.Regexp) []searchResult {
+	if re == nil {
+		return nil
+	}
+
+	var results []searchResult
+	for _, h := range histories {
+		h.mu.Lock()
+		for _, key := range sortedTestKeys(h.TestCases) {
+			var builder strings.Builder
+			for _, te := range h.eventsFor(key) {
+				builder.WriteString(te.Output)
+			}
+			if hits := len(matchPositions(plainText(parseANSI(builder.String())), re)); hits > 0 {
+				results = append(results, searchResult{history: h, key: key, hits: hits})
+			}
+		}
+		h.mu.Unlock()
+	}
+	return results
+}
+
+// searchPanel lists the tests whose logs match a query in the current or in all histories
+type searchPanel struct {
+	*tview.Flex
+	input   *tview.InputField
+	list    *tview.List
+	results []searchResult
+	re      *regexp.Regexp
+	err     error
+	opts    searchOptions
+	all     bool
+}
+
+// newSearchPanel creates the global search panel using the log search modes. search runs a
+// compiled query, jump is called with the selected result, the query and the modes used,
+// and done closes the panel.
+func newSearchPanel(app *tview.Application, opts searchOptions, search func(re *regexp.Regexp, all bool) []searchResult, jump func(searchResult, *regexp.Regexp, searchOptions), done func()) *searchPanel {
+	p := &searchPanel{
+		Flex: tview.NewFlex().SetDirection(tview.FlexRow),
+		input: tview.NewInputField().SetLabel("search: ").SetFieldWidth(0).
+			SetPlaceholder("Enter: search, Tab: results, Ctrl-A: all histories, Ctrl-R: regex, Ctrl-T: case"),
+		list: tview.NewList().ShowSecondaryText(false),
+		opts: opts,
+	}
+	p.AddItem(p.input, 1, 0, true).
+		AddItem(p.list, 0, 1, false)
+	p.SetBorder(true)
+	p.updateTitle()
+
+	run := func() {
+		p.results, p.re, p.err = nil, nil, nil
+		if query := p.input.GetText(); query != "" {
+			p.re, p.err = p.opts.compile(query)
+		}
+		if p.err == nil {
+			p.results = search(p.re, p.all)
+		}
+		p.list.Clear()
+		for _, r := range p.results {
+			p.list.AddItem(p.itemText(r), "", 0, nil)
+		}
+		p.updateTitle()
+	}
+
+	p.input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			run()
+			if len(p.results) > 0 {
+				app.SetFocus(p.list)
+			}
+		case tcell.KeyEscape:
+			done()
+		}
+	})
+	p.list.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		jump(p.results[index], p.re, p.opts)
+	})
+	p.list.SetDoneFunc(done)
+
+	// Tab switches between the query and the results, Ctrl-A toggles searching all histories,
+	// Ctrl-R and Ctrl-T change the search modes
+	p.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTab:
+			if p.input.HasFocus() {
+				app.SetFocus(p.list)
+			} else {
+				app.SetFocus(p.input)
+			}
+			return nil
+		case tcell.KeyCtrlA:
+			p.all = !p.all
+			run()
+			return nil
+		case tcell.KeyCtrlR:
+			p.opts.toggleRegex()
+			run()
+			return nil
+		case tcell.KeyCtrlT:
+			p.opts.nextCaseMode()
+			run()
+			return nil
+		}
+		return event
+	})
+	return p
+}
+
+// updateTitle shows the search scope and the number of results in the border
+func (p *searchPanel) updateTitle() {
+	scope := "current history"
+	if p.all {
+		scope = "all histories"
+	}
+	if modes := p.opts.String(); modes != "" {
+		scope += ", " + modes
+	}
+	title := fmt.Sprintf("Search logs (%s)", scope)
+	switch {
+	case p.err != nil:
+		title += " invalid regex"
+	case p.re != nil:
+		hits := 0
+		for _, r := range p.results {
+			hits += r.hits
+		}
+		title += fmt.Sprintf(" %d hits in %d logs", hits, len(p.results))
+	}
+	p.SetTitle(title)
+}
+
+// itemText formats a result for the list, prefixed with its history when searching all histories
+func (p *searchPanel) itemText(r searchResult) string {
+	name := lastPathComponent(r.key.Package) + " " + r.key.Test
+	if r.key.Test == "" {
+		name = "📦 " + r.key.Package
+	}
+	text := fmt.Sprintf("%s (%d)", name, r.hits)
+	if p.all {
+		text = r.history.Name + ": " + text
+	}
+	return tview.Escape(text)
+}
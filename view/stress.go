@@ -0,0 +1,225 @@
+package view
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// stressIteration is a run of the stressed test in one of the go test processes
+type stressIteration struct {
+	number  int // Order in which the iterations started, from 1
+	worker  int
+	action  collector.Action
+	elapsed float64
+	output  strings.Builder
+}
+
+// stressRun tallies the iterations of a stress run and keeps the logs of the failing ones
+type stressRun struct {
+	mu       sync.Mutex
+	key      collector.TestKey
+	opts     collector.StressOptions
+	started  int
+	passes   int
+	failures int
+	skips    int
+	failed   []*stressIteration
+	current  map[int]*stressIteration // Iteration in progress of each process
+}
+
+// newStressRun creates the tally of a stress run of a test
+func newStressRun(key collector.TestKey, opts collector.StressOptions) *stressRun {
+	return &stressRun{key: key, opts: opts, current: make(map[int]*stressIteration)}
+}
+
+// record adds an event of the stress run. Events of the test and its subtests between a run event
+// of the test and its terminal event make up an iteration.
+func (s *stressRun) record(e collector.StressEvent) {
+	te := e.Event
+	if te.Package != s.key.Package || (te.Test != s.key.Test && !strings.HasPrefix(te.Test, s.key.Test+"/")) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.current[e.Worker]
+	if te.Test == s.key.Test && te.Action == collector.ActionRun {
+		s.started++
+		it = &stressIteration{number: s.started, worker: e.Worker}
+		s.current[e.Worker] = it
+	}
+	if it == nil {
+		return
+	}
+	it.output.WriteString(te.Output)
+	if te.Test != s.key.Test || !te.Action.IsTerminal() {
+		return
+	}
+
+	it.action, it.elapsed = te.Action, te.Elapsed
+	delete(s.current, e.Worker)
+	switch te.Action {
+	case collector.ActionPass:
+		s.passes++
+	case collector.ActionFail:
+		s.failures++
+		s.failed = append(s.failed, it)
+	case collector.ActionSkip:
+		s.skips++
+	}
+}
+
+// summary returns the tally of the iterations and the pass rate
+func (s *stressRun) summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := s.passes + s.failures + s.skips
+	text := fmt.Sprintf("%d/%d runs: ✓%d ✗%d", done, s.opts.Iterations, s.passes, s.failures)
+	if s.skips > 0 {
+		text += fmt.Sprintf(" ⏭%d", s.skips)
+	}
+	if s.passes+s.failures > 0 {
+		text += fmt.Sprintf(", %.1f%% pass", 100*float64(s.passes)/float64(s.passes+s.failures))
+	}
+	return text
+}
+
+// failedIterations returns the failing iterations recorded so far
+func (s *stressRun) failedIterations() []*stressIteration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*stressIteration(nil), s.failed...)
+}
+
+// newStressForm builds a form to set up the stress run of a test.
+// done is called with the options when the user confirms, cancel otherwise.
+func newStressForm(target string, done func(collector.StressOptions), cancel func()) *tview.Form {
+	form := tview.NewForm()
+	form.AddInputField("Iterations", "100", 10, tview.InputFieldInteger, nil)
+	form.AddInputField("Parallel processes", "1", 10, tview.InputFieldInteger, nil)
+	form.AddCheckbox("Stop at first failure", false, nil)
+
+	form.AddButton("Run", func() {
+		iterations, _ := strconv.Atoi(form.GetFormItemByLabel("Iterations").(*tview.InputField).GetText())
+		parallel, _ := strconv.Atoi(form.GetFormItemByLabel("Parallel processes").(*tview.InputField).GetText())
+		if iterations < 1 || parallel < 1 {
+			form.SetTitle("Stress " + target + ": iterations and processes must be positive")
+			return
+		}
+		done(collector.StressOptions{
+			Iterations:   iterations,
+			Parallel:     parallel,
+			UntilFailure: form.GetFormItemByLabel("Stop at first failure").(*tview.Checkbox).IsChecked(),
+		})
+	})
+	form.AddButton("Cancel", cancel)
+	form.SetCancelFunc(cancel)
+
+	form.SetBorder(true).SetTitle("Stress " + target)
+	return form
+}
+
+// stressPanel shows the live tally of a stress run and the logs of its failing iterations
+type stressPanel struct {
+	*tview.Flex
+	run     *stressRun
+	history *History
+	summary *tview.TextView
+	list    *tview.List
+	log     *tview.TextView
+	failed  []*stressIteration
+}
+
+// newStressPanel creates the panel of the stress run of a history. done is called when
+// the panel is closed with Esc, the run goes on in the background.
+func newStressPanel(app *tview.Application, h *History, done func()) *stressPanel {
+	p := &stressPanel{
+		Flex:    tview.NewFlex().SetDirection(tview.FlexRow),
+		run:     h.stress,
+		history: h,
+		summary: tview.NewTextView(),
+		list:    tview.NewList().ShowSecondaryText(false),
+		log:     tview.NewTextView().SetDynamicColors(true),
+	}
+	p.list.SetBorder(true).SetTitle("Failing iterations")
+	p.log.SetBorder(true).SetTitle("Log")
+	p.AddItem(p.summary, 1, 0, false).
+		AddItem(tview.NewFlex().
+			AddItem(p.list, 30, 0, true).
+			AddItem(p.log, 0, 1, false), 0, 1, true)
+	p.SetBorder(true)
+	p.SetTitle(tview.Escape(h.Name))
+
+	p.list.SetChangedFunc(func(index int, _, _ string, _ rune) {
+		p.showLog(index)
+	})
+	p.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEsc:
+			done()
+		case event.Key() == tcell.KeyTab:
+			if p.list.HasFocus() {
+				app.SetFocus(p.log)
+			} else {
+				app.SetFocus(p.list)
+			}
+		case event.Rune() == 'c':
+			h.Cancel()
+		case event.Rune() == 'j' && p.list.HasFocus():
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		case event.Rune() == 'k' && p.list.HasFocus():
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		default:
+			return event
+		}
+		return nil
+	})
+	return p
+}
+
+// Draw updates the tally and the failing iterations before drawing the panel
+func (p *stressPanel) Draw(screen tcell.Screen) {
+	state := "running"
+	switch p.history.State {
+	case StateCancelled:
+		state = "cancelled"
+	case StateCompleted, StateFailed, StateIdle:
+		state = "done"
+	}
+	processes := "1 process"
+	if n := min(p.run.opts.Parallel, p.run.opts.Iterations); n > 1 {
+		processes = fmt.Sprintf("%d processes", n)
+	}
+	until := ""
+	if p.run.opts.UntilFailure {
+		until = ", until first failure"
+	}
+	p.summary.SetText(fmt.Sprintf("%s (%s%s, %s)", p.run.summary(), processes, until, state))
+
+	failed := p.run.failedIterations()
+	for _, it := range failed[len(p.failed):] {
+		p.list.AddItem(fmt.Sprintf("#%d ✗ [%.3fs] process %d", it.number, it.elapsed, it.worker+1), "", 0, nil)
+	}
+	first := len(p.failed) == 0 && len(failed) > 0
+	p.failed = failed
+	if first {
+		p.showLog(0)
+	}
+	p.Flex.Draw(screen)
+}
+
+// showLog shows the log of a failing iteration
+func (p *stressPanel) showLog(index int) {
+	if index < 0 || index >= len(p.failed) {
+		return
+	}
+	it := p.failed[index]
+	p.log.SetTitle(fmt.Sprintf("Log #%d", it.number))
+	p.log.SetText(renderLog(parseANSI(it.output.String()), nil, -1)).ScrollToBeginning()
+}
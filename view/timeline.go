@@ -0,0 +1,334 @@
+package view
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shooooooooono/gotestui/collector"
+)
+
+// timelineSpan is an interval during which a package or test was running
+type timelineSpan struct {
+	start, end time.Time
+}
+
+// timelineRow is a package or test of the timeline
+type timelineRow struct {
+	key        collector.TestKey
+	depth      int
+	spans      []timelineSpan
+	start, end time.Time // From the first start to the last end, including pauses
+	color      tcell.Color
+	leaf       bool
+}
+
+// running returns the total time spent running
+func (r timelineRow) running() time.Duration {
+	var d time.Duration
+	for _, s := range r.spans {
+		d += s.end.Sub(s.start)
+	}
+	return d
+}
+
+// runningAt reports whether one of the spans overlaps the interval [from, to)
+func (r timelineRow) runningAt(from, to time.Time) bool {
+	for _, s := range r.spans {
+		if s.start.Before(to) && s.end.After(from) {
+			return true
+		}
+	}
+	return false
+}
+
+// testSpans returns the intervals between the run or cont events of a test and the following
+// pause or terminal events. A test still running at the end of the events runs until now.
+// Packages run from their start event to their terminal event.
+func testSpans(events []collector.TestEvent, now time.Time) []timelineSpan {
+	var spans []timelineSpan
+	var start time.Time
+	for _, te := range events {
+		if te.Time.IsZero() {
+			continue
+		}
+		switch {
+		case te.Action == collector.ActionRun || te.Action == collector.ActionCont || te.Action == collector.ActionStart:
+			if start.IsZero() {
+				start = te.Time
+			}
+		case te.Action == collector.ActionPause || te.Action.IsTerminal():
+			if !start.IsZero() {
+				spans = append(spans, timelineSpan{start: start, end: te.Time})
+				start = time.Time{}
+			}
+		}
+	}
+	if !start.IsZero() {
+		spans = append(spans, timelineSpan{start: start, end: now})
+	}
+	return spans
+}
+
+// buildTimeline returns the timeline rows of a history: each package followed by its tests,
+// subtests below their parent and siblings ordered by start time
+func buildTimeline(h *History, now time.Time) []timelineRow {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var rows []timelineRow
+	rowsOf := make(map[collector.TestKey]timelineRow)
+	children := make(map[collector.TestKey][]collector.TestKey)
+	var packages []collector.TestKey
+	for _, key := range sortedTestKeys(h.TestCases) {
+		events := h.eventsFor(key)
+		spans := testSpans(events, now)
+		if len(spans) == 0 {
+			continue
+		}
+		row := timelineRow{key: key, spans: spans, start: spans[0].start, end: spans[len(spans)-1].end, leaf: true}
+		if key.Test == "" {
+			_, row.color, _, _ = resolvePackageStatus(events, "")
+			packages = append(packages, key)
+		} else {
+			_, row.color, _ = resolveTestStatus(events, "")
+			row.depth = strings.Count(key.Test, "/") + 1
+			parent := collector.TestKey{Package: key.Package}
+			if idx := strings.LastIndex(key.Test, "/"); idx >= 0 {
+				parent.Test = key.Test[:idx]
+			}
+			children[parent] = append(children[parent], key)
+		}
+		rowsOf[key] = row
+	}
+
+	var add func(key collector.TestKey)
+	add = func(key collector.TestKey) {
+		row := rowsOf[key]
+		kids := children[key]
+		row.leaf = len(kids) == 0
+		rows = append(rows, row)
+		sort.SliceStable(kids, func(i, j int) bool {
+			return rowsOf[kids[i]].start.Before(rowsOf[kids[j]].start)
+		})
+		for _, kid := range kids {
+			add(kid)
+		}
+	}
+	for _, pkg := range packages {
+		add(pkg)
+	}
+	return rows
+}
+
+// peakParallelism returns the highest number of leaf tests running at the same time
+func peakParallelism(rows []timelineRow) int {
+	type change struct {
+		at    time.Time
+		delta int
+	}
+	var changes []change
+	for _, r := range rows {
+		if r.key.Test == "" || !r.leaf {
+			continue
+		}
+		for _, s := range r.spans {
+			changes = append(changes, change{s.start, 1}, change{s.end, -1})
+		}
+	}
+	// Tests ending when others start do not overlap
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].at.Equal(changes[j].at) {
+			return changes[i].delta < changes[j].delta
+		}
+		return changes[i].at.Before(changes[j].at)
+	})
+	peak, current := 0, 0
+	for _, c := range changes {
+		current += c.delta
+		peak = max(peak, current)
+	}
+	return peak
+}
+
+// timelinePanel plots the running intervals of the packages and tests of a history,
+// Gantt style. Paused or waiting periods are shaded.
+type timelinePanel struct {
+	*tview.Box
+	history      *History
+	rows         []timelineRow
+	selectedKey  collector.TestKey
+	selected     int
+	offset       int
+	selectedFunc func(collector.TestKey)
+	doneFunc     func()
+}
+
+// newTimelinePanel creates the timeline of a history with current selected. selected is called
+// with the test chosen with Enter, done when the panel is closed with Esc or T.
+func newTimelinePanel(h *History, current collector.TestKey, selected func(collector.TestKey), done func()) *timelinePanel {
+	t := &timelinePanel{
+		Box:          tview.NewBox(),
+		history:      h,
+		selectedKey:  current,
+		selectedFunc: selected,
+		doneFunc:     done,
+	}
+	t.SetBorder(true)
+	t.refresh()
+	return t
+}
+
+// refresh rebuilds the rows, keeping the selected test
+func (t *timelinePanel) refresh() {
+	t.rows = buildTimeline(t.history, time.Now())
+	t.selected = 0
+	for i, r := range t.rows {
+		if r.key == t.selectedKey {
+			t.selected = i
+			break
+		}
+	}
+	if len(t.rows) > 0 {
+		t.selectedKey = t.rows[t.selected].key
+	}
+
+	title := "Timeline"
+	if origin, finish, ok := t.bounds(); ok {
+		title = fmt.Sprintf("Timeline [%.3fs, peak %d parallel]", finish.Sub(origin).Seconds(), peakParallelism(t.rows))
+	}
+	t.SetTitle(title)
+}
+
+// bounds returns the earliest start and the latest end of the rows
+func (t *timelinePanel) bounds() (origin, finish time.Time, ok bool) {
+	for i, r := range t.rows {
+		if i == 0 || r.start.Before(origin) {
+			origin = r.start
+		}
+		if i == 0 || r.end.After(finish) {
+			finish = r.end
+		}
+	}
+	return origin, finish, len(t.rows) > 0
+}
+
+// rowLabel returns the indented name of a row
+func rowLabel(r timelineRow) string {
+	if r.key.Test == "" {
+		return "📦 " + lastPathComponent(r.key.Package)
+	}
+	return strings.Repeat(" ", r.depth) + lastPathComponent(r.key.Test)
+}
+
+// Draw draws the time axis, a bar per row and the details of the selected row
+func (t *timelinePanel) Draw(screen tcell.Screen) {
+	t.refresh()
+	t.DrawForSubclass(screen, t)
+	x, y, width, height := t.GetInnerRect()
+	origin, finish, ok := t.bounds()
+	if !ok {
+		tview.Print(screen, "No timing information", x, y, width, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+
+	// Columns: label, bars and running time
+	labelWidth := 0
+	for _, r := range t.rows {
+		labelWidth = max(labelWidth, tview.TaggedStringWidth(tview.Escape(rowLabel(r))))
+	}
+	labelWidth = min(labelWidth, width/3)
+	const timeWidth = 10
+	barWidth := width - labelWidth - timeWidth - 2
+	if barWidth < 10 || height < 3 {
+		return
+	}
+	barX := x + labelWidth + 1
+	total := finish.Sub(origin)
+	step := total / time.Duration(barWidth)
+	if step <= 0 {
+		step = 1
+	}
+
+	// Time axis, labelled every 16 columns
+	for col := 0; col < barWidth; col += 16 {
+		label := fmt.Sprintf("|%.1fs", (time.Duration(col) * step).Seconds())
+		tview.Print(screen, label, barX+col, y, min(16, barWidth-col), tview.AlignLeft, tcell.ColorGray)
+	}
+
+	// Keep the selection visible between the axis and the details line
+	visible := height - 2
+	if t.selected < t.offset {
+		t.offset = t.selected
+	} else if t.selected >= t.offset+visible {
+		t.offset = t.selected - visible + 1
+	}
+
+	for i := 0; i < visible && t.offset+i < len(t.rows); i++ {
+		index := t.offset + i
+		r := t.rows[index]
+		rowY := y + 1 + i
+
+		label := tview.Escape(rowLabel(r))
+		if index == t.selected {
+			label = "[::r]" + label
+		}
+		tview.Print(screen, label, x, rowY, labelWidth, tview.AlignLeft, tcell.ColorWhite)
+
+		for col := 0; col < barWidth; col++ {
+			from := origin.Add(time.Duration(col) * step)
+			to := from.Add(step)
+			switch {
+			case r.runningAt(from, to):
+				screen.SetContent(barX+col, rowY, '█', nil, tcell.StyleDefault.Foreground(r.color))
+			case r.start.Before(to) && r.end.After(from):
+				screen.SetContent(barX+col, rowY, '░', nil, tcell.StyleDefault.Foreground(tcell.ColorGray))
+			}
+		}
+
+		tview.Print(screen, fmt.Sprintf("%.3fs", r.running().Seconds()), barX+barWidth+1, rowY, timeWidth, tview.AlignRight, tcell.ColorWhite)
+	}
+
+	// Details of the selected row
+	r := t.rows[t.selected]
+	name := r.key.Test
+	if name == "" {
+		name = r.key.Package
+	}
+	details := fmt.Sprintf("%s: +%.3fs → +%.3fs, running %.3fs, paused %.3fs",
+		name, r.start.Sub(origin).Seconds(), r.end.Sub(origin).Seconds(),
+		r.running().Seconds(), (r.end.Sub(r.start) - r.running()).Seconds())
+	tview.Print(screen, tview.Escape(details), x, y+height-1, width, tview.AlignLeft, tcell.ColorYellow)
+}
+
+// InputHandler moves the selection with j/k, arrows, g/G and jumps to the selected test with Enter
+func (t *timelinePanel) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return t.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if len(t.rows) == 0 {
+			if event.Key() == tcell.KeyEsc || event.Rune() == 'T' {
+				t.doneFunc()
+			}
+			return
+		}
+		switch {
+		case event.Key() == tcell.KeyDown || event.Rune() == 'j':
+			t.selected = min(t.selected+1, len(t.rows)-1)
+		case event.Key() == tcell.KeyUp || event.Rune() == 'k':
+			t.selected = max(t.selected-1, 0)
+		case event.Key() == tcell.KeyHome || event.Rune() == 'g':
+			t.selected = 0
+		case event.Key() == tcell.KeyEnd || event.Rune() == 'G':
+			t.selected = len(t.rows) - 1
+		case event.Key() == tcell.KeyEnter:
+			t.selectedFunc(t.rows[t.selected].key)
+			return
+		case event.Key() == tcell.KeyEsc || event.Rune() == 'T':
+			t.doneFunc()
+			return
+		}
+		t.selectedKey = t.rows[t.selected].key
+	})
+}
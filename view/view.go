@@ -1,7 +1,11 @@
 package view
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -12,7 +16,8 @@ import (
 	"github.com/shooooooooono/gotestui/collector"
 )
 
-type TestCaseMap map[string][]collector.TestEvent
+// TestCaseMap holds the events of each test, keyed by package and test name
+type TestCaseMap map[collector.TestKey][]collector.TestEvent
 
 // HistoryState represents the state of a history
 type HistoryState int
@@ -22,6 +27,7 @@ const (
 	StateRunning                       // Running
 	StateCompleted                     // Success
 	StateFailed                        // Failed
+	StateCancelled                     // Cancelled by the user
 )
 
 // History represents a single test run session
@@ -31,7 +37,20 @@ type History struct {
 	NodeMap   map[string]*tview.TreeNode
 	Root      *tview.TreeNode
 	State     HistoryState
-	mu        sync.Mutex
+	// BuildEvents holds build-output and build-fail events keyed by ImportPath
+	BuildEvents map[string][]collector.TestEvent
+	// Args holds the go test arguments reruns are based on, nil when the events were piped or imported
+	Args *collector.TestArgs
+	mu   sync.Mutex
+
+	children map[*tview.TreeNode][]*tview.TreeNode // Unfiltered children of each tree node
+	counts   map[*tview.TreeNode]statusCounts      // Leaf test counts of nodes with children
+	flaky    map[collector.TestKey]bool            // Tests shown as flaky
+	stress   *stressRun                            // Tally of a stress run, nil for other runs
+	partial  map[string]bool                       // Packages of which the run selected only some tests
+
+	cancel    context.CancelFunc // Stops the go test run, nil when the events cannot be cancelled
+	cancelled bool
 }
 
 // NewHistory creates a new history with the given name
@@ -39,13 +58,119 @@ func NewHistory(name string) *History {
 	root := tview.NewTreeNode(".")
 	root.SetExpanded(true)
 	return &History{
-		Name:      name,
-		TestCases: make(TestCaseMap),
-		NodeMap:   make(map[string]*tview.TreeNode),
-		Root:      root,
+		Name:        name,
+		TestCases:   make(TestCaseMap),
+		NodeMap:     make(map[string]*tview.TreeNode),
+		Root:        root,
+		BuildEvents: make(map[string][]collector.TestEvent),
+		children:    make(map[*tview.TreeNode][]*tview.TreeNode),
+		counts:      make(map[*tview.TreeNode]statusCounts),
+		flaky:       make(map[collector.TestKey]bool),
 	}
 }
 
+// addEvent records a test event and returns a copy of all events of its test.
+// It returns false when the event has no node to update, e.g. for build events.
+func (h *History) addEvent(te collector.TestEvent) (collector.TestKey, []collector.TestEvent, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if te.IsBuildEvent() {
+		h.BuildEvents[te.ImportPath] = append(h.BuildEvents[te.ImportPath], te)
+		return collector.TestKey{}, nil, false
+	}
+	if te.Package == "" {
+		return collector.TestKey{}, nil, false
+	}
+
+	key := te.Key()
+	h.TestCases[key] = append(h.TestCases[key], te)
+	return key, h.eventsFor(key), true
+}
+
+// eventsFor returns a copy of the events of a test. For packages that failed to build,
+// the output of the failed build is included first. The caller must hold h.mu.
+func (h *History) eventsFor(key collector.TestKey) []collector.TestEvent {
+	var events []collector.TestEvent
+	if key.Test == "" {
+		for _, te := range h.TestCases[key] {
+			if te.FailedBuild != "" {
+				events = append(events, h.BuildEvents[te.FailedBuild]...)
+			}
+		}
+	}
+	return append(events, h.TestCases[key]...)
+}
+
+// allEvents returns the events of every test in the history, ordered by time
+func (h *History) allEvents() []collector.TestEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var events []collector.TestEvent
+	for _, buildEvents := range h.BuildEvents {
+		events = append(events, buildEvents...)
+	}
+	for _, key := range sortedTestKeys(h.TestCases) {
+		events = append(events, h.TestCases[key]...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// sortedTestKeys returns the keys of the test cases ordered by package and test name
+func sortedTestKeys(testCases TestCaseMap) []collector.TestKey {
+	keys := make([]collector.TestKey, 0, len(testCases))
+	for key := range testCases {
+		keys = append(keys, key)
+	}
+	sortTestKeys(keys)
+	return keys
+}
+
+// sortTestKeys orders test keys by package and test name
+func sortTestKeys(keys []collector.TestKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Package != keys[j].Package {
+			return keys[i].Package < keys[j].Package
+		}
+		return keys[i].Test < keys[j].Test
+	})
+}
+
+// addChild adds a node to the tree, remembering it for filtering
+func (h *History) addChild(parent, node *tview.TreeNode) {
+	parent.AddChild(node)
+	h.children[parent] = append(h.children[parent], node)
+}
+
+// Cancel stops the go test run of the history and reports whether it was running
+func (h *History) Cancel() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.State != StateRunning || h.cancel == nil {
+		return false
+	}
+	h.cancelled = true
+	h.cancel()
+	return true
+}
+
+// finish sets the final state once every event of the history has been processed
+func (h *History) finish() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cancelled {
+		h.State = StateCancelled
+		return
+	}
+	h.State = stateFromTestResult(h.TestCases)
+}
+
 // HistoryManager manages multiple test histories
 type HistoryManager struct {
 	Histories    []*History
@@ -94,13 +219,39 @@ func (hm *HistoryManager) Prev() bool {
 	return false
 }
 
+// Options configures the TUI application
+type Options struct {
+	// Context stops the application and every run when done, nil for no limit
+	Context context.Context
+	// TestArgs holds the go test arguments when gotestui launched the initial run itself
+	TestArgs *collector.TestArgs
+	// Cancel stops the initial run, nil when the events are piped or imported
+	Cancel context.CancelFunc
+	// Watch reruns the packages affected by changed .go files in a new history
+	Watch bool
+	// SelectFailure moves the tree cursor to the first failure when the current history finishes
+	SelectFailure bool
+}
+
 // CreateApplication creates and starts the TUI application
-func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan struct{}) {
+func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan struct{}, opts Options) {
 	app := tview.NewApplication()
 
 	historyMgr := NewHistoryManager()
 	initialHistory := historyMgr.AddHistory("Initial")
 	initialHistory.State = StateRunning
+	initialHistory.Args = opts.TestArgs
+	initialHistory.cancel = opts.Cancel
+
+	// Context and wait group of all reruns, so they can be stopped when the application exits
+	parentCtx := opts.Context
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	runCtx, cancelRuns := context.WithCancel(parentCtx)
+	var runs sync.WaitGroup
+	stopApp := context.AfterFunc(parentCtx, app.Stop)
+	defer stopApp()
 
 	// History list
 	historyList := tview.NewList()
@@ -111,9 +262,16 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 	// Test tree
 	treeView := tview.NewTreeView()
 	treeView.SetBorder(true).SetTitle("Tests").SetBorderColor(tcell.ColorWhite) // Initial focus
-	treeView.SetGraphics(false) // Use indentation instead of tree lines
+	treeView.SetGraphics(false)                                                 // Use indentation instead of tree lines
 	treeView.SetRoot(initialHistory.Root).SetCurrentNode(initialHistory.Root)
 
+	// Tree filter, kept across history switches
+	treeFilter := &TreeFilter{}
+	filterInput := tview.NewInputField().
+		SetLabel("filter: ").
+		SetFieldWidth(0)
+	filterMode := false
+
 	// Log view
 	textView := tview.NewTextView().
 		SetDynamicColors(true).
@@ -123,8 +281,9 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 	textView.SetBorder(true).SetTitle("Log").SetBorderColor(tcell.ColorGray)
 
 	// Search state
-	searchQuery := ""
-	searchMatches := []int{} // Line numbers with matches
+	var searchRe *regexp.Regexp // Current log search, nil when not searching
+	searchOpts := searchOptions{}
+	searchMatches := []int{} // Line number of each match
 	searchIndex := 0
 
 	// Search input field
@@ -152,7 +311,74 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 		}
 	}
 
-	// Update border colors based on focus
+	// Move the tree cursor to the next or previous failure, from the top when first is set
+	selectFailure := func(backward, first bool) {
+		current := treeView.GetCurrentNode()
+		if first {
+			current = nil
+		}
+		if path := findFailure(treeView.GetRoot(), current, backward); path != nil {
+			selectPath(treeView, path)
+			viewLog(path[len(path)-1], textView, searchRe, -1)
+		}
+	}
+
+	// Reapply the tree filter to the current history
+	refreshFilter := func() {
+		if h := historyMgr.Current(); h != nil {
+			applyFilter(h, treeFilter)
+		}
+		treeView.SetTitle(treeFilter.Title())
+	}
+
+	leftPanel := tview.NewFlex().SetDirection(tview.FlexRow)
+
+	// Name filter input: the tree is filtered while typing, Ctrl-R toggles regular expressions,
+	// Enter keeps the filter and Esc clears it
+	hideFilterInput := func() {
+		if filterMode {
+			filterMode = false
+			leftPanel.RemoveItem(filterInput)
+		}
+		app.SetFocus(treeView)
+	}
+	filterInput.SetChangedFunc(func(text string) {
+		treeFilter.SetName(text, treeFilter.Regex)
+		refreshFilter()
+	})
+	filterInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlR {
+			treeFilter.SetName(filterInput.GetText(), !treeFilter.Regex)
+			if treeFilter.Regex {
+				filterInput.SetLabel("filter (regex): ")
+			} else {
+				filterInput.SetLabel("filter: ")
+			}
+			refreshFilter()
+			return nil
+		}
+		return event
+	})
+	filterInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			treeFilter.SetName("", treeFilter.Regex)
+			refreshFilter()
+		}
+		hideFilterInput()
+	})
+
+	// Pages show dialogs on top of the main layout
+	pages := tview.NewPages()
+
+	// Usage view, showing the keys of the focused panel
+	usageView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWordWrap(false).
+		SetMaxLines(1)
+	usageView.SetText(treeUsage)
+
+	// Update border colors and usage based on focus
 	updateFocus := func(p tview.Primitive) {
 		historyList.SetBorderColor(tcell.ColorGray)
 		treeView.SetBorderColor(tcell.ColorGray)
@@ -160,27 +386,38 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 		switch p {
 		case historyList:
 			historyList.SetBorderColor(tcell.ColorWhite)
+			usageView.SetText(historyUsage)
 		case treeView:
 			treeView.SetBorderColor(tcell.ColorWhite)
+			usageView.SetText(treeUsage)
 		case textView:
 			textView.SetBorderColor(tcell.ColorWhite)
+			usageView.SetText(logUsage)
 		}
 	}
 
-	// Find all matching lines
-	findMatches := func(query string) {
+	// Find all matches and their lines
+	findMatches := func(re *regexp.Regexp) {
 		searchMatches = []int{}
-		if query == "" {
+		searchIndex = 0
+		if re == nil {
 			return
 		}
-		content := textView.GetText(true)
-		lines := strings.Split(content, "\n")
-		for i, line := range lines {
-			if strings.Contains(strings.ToLower(line), strings.ToLower(query)) {
-				searchMatches = append(searchMatches, i)
-			}
+		searchMatches = matchLines(plainText(parseANSI(nodeOutput(treeView.GetCurrentNode()))), re)
+	}
+
+	// Set the log title with a search status and the active search modes
+	setLogTitle := func(status string) {
+		title := "Log"
+		if status != "" {
+			title += " [" + status + "]"
 		}
-		searchIndex = 0
+		if searchRe == locationPattern {
+			title += " (locations)"
+		} else if modes := searchOpts.String(); modes != "" && searchRe != nil {
+			title += " (" + modes + ")"
+		}
+		textView.SetTitle(title)
 	}
 
 	// Jump to match and re-render with current match highlighted
@@ -194,32 +431,116 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 			index = 0
 		}
 		searchIndex = index
-		viewLog(treeView.GetCurrentNode(), textView, searchQuery, searchIndex)
+		viewLog(treeView.GetCurrentNode(), textView, searchRe, searchIndex)
 		textView.ScrollTo(searchMatches[searchIndex], 0)
-		textView.SetTitle(fmt.Sprintf("Log [%d/%d]", searchIndex+1, len(searchMatches)))
+		setLogTitle(fmt.Sprintf("%d/%d", searchIndex+1, len(searchMatches)))
+	}
+
+	// Package directories the file locations of the logs are relative to, and the packages
+	// whose directory is being resolved
+	pkgDirs := make(map[string]string)
+	resolving := make(map[string]bool)
+
+	// Open the current file location of the log in the editor, or the first one when not
+	// cycling through locations, suspending the TUI while the editor runs. The package
+	// directory is resolved with go list in the background the first time.
+	var openLocation func()
+	openLocation = func() {
+		node := treeView.GetCurrentNode()
+		ref, _ := node.GetReference().(*nodeRef)
+		text := plainText(parseANSI(nodeOutput(node)))
+		locations := matchPositions(text, locationPattern)
+		if ref == nil || len(locations) == 0 {
+			setLogTitle("no location")
+			return
+		}
+		index := 0
+		if searchRe == locationPattern && searchIndex < len(locations) {
+			index = searchIndex
+		}
+		file, line := splitLocation(text[locations[index][0]:locations[index][1]])
+
+		pkg := ref.key.Package
+		pkgDir, ok := pkgDirs[pkg]
+		if !ok {
+			if resolving[pkg] {
+				return
+			}
+			resolving[pkg] = true
+			setLogTitle("resolving " + pkg)
+			var args collector.TestArgs
+			if h := historyMgr.Current(); h != nil && h.Args != nil {
+				args = *h.Args
+			}
+			go func() {
+				// Outside of the module the package cannot be found, paths are then tried as is
+				dir, _ := collector.PackageDir(runCtx, args, pkg)
+				app.QueueUpdateDraw(func() {
+					delete(resolving, pkg)
+					pkgDirs[pkg] = dir
+					// Open the location unless the user moved on in the meantime
+					if treeView.GetCurrentNode() == node {
+						setLogTitle("")
+						openLocation()
+					}
+				})
+			}()
+			return
+		}
+		path, err := resolveLocation(file, pkgDir)
+		if err != nil {
+			setLogTitle(err.Error())
+			return
+		}
+		app.Suspend(func() {
+			err = editorCommand(path, line).Run()
+		})
+		if err != nil {
+			setLogTitle("editor failed: " + err.Error())
+		}
 	}
 
 	// Search input handlers
 	searchInput.SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEnter {
-			searchQuery = searchInput.GetText()
-			findMatches(searchQuery)
-			if len(searchMatches) > 0 {
+		query := searchInput.GetText()
+		if key == tcell.KeyEnter && query != "" {
+			re, err := searchOpts.compile(query)
+			searchRe = re
+			findMatches(searchRe)
+			switch {
+			case err != nil:
+				viewLog(treeView.GetCurrentNode(), textView, nil, -1)
+				setLogTitle("invalid regex")
+			case len(searchMatches) > 0:
 				jumpToMatch(0)
-			} else {
-				viewLog(treeView.GetCurrentNode(), textView, searchQuery, -1)
-				textView.SetTitle("Log [no match]")
+			default:
+				viewLog(treeView.GetCurrentNode(), textView, searchRe, -1)
+				setLogTitle("no match")
 			}
 		} else {
-			searchQuery = ""
-			viewLog(treeView.GetCurrentNode(), textView, searchQuery, -1)
-			textView.SetTitle("Log")
+			searchRe = nil
+			viewLog(treeView.GetCurrentNode(), textView, nil, -1)
+			setLogTitle("")
 		}
 		hideSearchInput()
 		searchInput.SetText("")
 		app.SetFocus(textView)
 	})
 
+	// Ctrl-R toggles regular expressions and Ctrl-T cycles the case mode while typing a search
+	searchInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlR:
+			searchOpts.toggleRegex()
+		case tcell.KeyCtrlT:
+			searchOpts.nextCaseMode()
+		default:
+			return event
+		}
+		searchInput.SetLabel(searchOpts.label())
+		return nil
+	})
+
 	// Log view input handling
 	textView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyRune {
@@ -254,6 +575,27 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 					jumpToMatch(searchIndex - 1)
 				}
 				return nil
+			case 'l', 'L':
+				// Cycle through the file locations, starting from the first one
+				next := 0
+				if searchRe == locationPattern {
+					next = searchIndex + 1
+					if event.Rune() == 'L' {
+						next = searchIndex - 1
+					}
+				}
+				searchRe = locationPattern
+				findMatches(searchRe)
+				if len(searchMatches) == 0 {
+					viewLog(treeView.GetCurrentNode(), textView, searchRe, -1)
+					setLogTitle("no location")
+					return nil
+				}
+				jumpToMatch(next)
+				return nil
+			case 'o':
+				openLocation()
+				return nil
 			}
 		}
 		// Enter to go back to tree view
@@ -264,25 +606,19 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 		}
 		// Escape to clear search
 		if event.Key() == tcell.KeyEsc {
-			searchQuery = ""
+			searchRe = nil
 			searchMatches = []int{}
-			viewLog(treeView.GetCurrentNode(), textView, searchQuery, -1)
+			viewLog(treeView.GetCurrentNode(), textView, nil, -1)
 			textView.SetTitle("Log")
 			return nil
 		}
 		return event
 	})
 
-	// Usage view
-	usageView := tview.NewTextView().
-		SetDynamicColors(true).
-		SetRegions(true).
-		SetWordWrap(false).
-		SetMaxLines(1)
-	usageView.SetText("q: quit, Tab: focus, Space: expand, Enter: log, r: rerun, e: export, /: search, n/N: next/prev")
-
 	// Flag to prevent recursive updates
 	updatingHistoryList := false
+	// History marked with 'm' to compare other histories with
+	var compareBase *History
 	spinnerFrames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	var spinnerFrame atomic.Int32
 
@@ -302,12 +638,22 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 			}
 
 			suffix := historyStateSuffix(h.State, spinnerFrames[spinnerFrame.Load()])
+			if h == compareBase {
+				suffix += " (base)"
+			}
 
 			historyList.AddItem(fmt.Sprintf("%s%s%s", prefix, h.Name, suffix), "", 0, nil)
 		}
 		historyList.SetCurrentItem(historyMgr.CurrentIndex)
 	}
 
+	// Flaky tests of all histories, updated when a history finishes
+	var flakyTests []flakyTest
+	refreshFlaky := func() {
+		flakyTests = findFlakyTests(historyMgr.Histories)
+		markFlaky(historyMgr.Histories, flakyTests, spinnerFrames[spinnerFrame.Load()])
+	}
+
 	// Animation ticker for running histories and tests
 	go func() {
 		ticker := time.NewTicker(100 * time.Millisecond)
@@ -330,12 +676,15 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 					currentHistory := historyMgr.Current()
 					if currentHistory != nil && currentHistory.State == StateRunning {
 						currentHistory.mu.Lock()
-						for testName, events := range currentHistory.TestCases {
+						for key, events := range currentHistory.TestCases {
 							if len(events) > 0 && isTestRunning(events) {
-								updateNode(currentHistory.Root, currentHistory.NodeMap, testName, events, spinnerFrames[spinnerFrame.Load()])
+								updateNode(currentHistory, key, events, spinnerFrames[spinnerFrame.Load()])
 							}
 						}
 						currentHistory.mu.Unlock()
+						if treeFilter.Active() {
+							applyFilter(currentHistory, treeFilter)
+						}
 					}
 				})
 			}
@@ -351,14 +700,55 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 		h := historyMgr.Current()
 		if h != nil {
 			// Reset search state when switching histories
-			searchQuery = ""
+			searchRe = nil
 			searchMatches = []int{}
 			searchIndex = 0
 			textView.SetTitle("Log")
+			applyFilter(h, treeFilter)
 			treeView.SetRoot(h.Root).SetCurrentNode(h.Root)
 			updateHistoryList()
-			viewLog(treeView.GetCurrentNode(), textView, searchQuery, -1)
+			viewLog(treeView.GetCurrentNode(), textView, searchRe, -1)
+		}
+	}
+
+	// Select the node of a test in the tree, switching history and clearing the filter hiding it
+	revealNode := func(history *History, key collector.TestKey) *tview.TreeNode {
+		for i, h := range historyMgr.Histories {
+			if h == history && i != historyMgr.CurrentIndex {
+				switchHistory(i)
+			}
+		}
+		node := history.NodeMap[nodeKey(key)]
+		if node == nil {
+			return nil
 		}
+		path := treeView.GetPath(node)
+		if path == nil {
+			// Hidden by the tree filter
+			*treeFilter = TreeFilter{}
+			refreshFilter()
+			path = treeView.GetPath(node)
+		}
+		selectPath(treeView, path)
+		return node
+	}
+
+	// Show the log of a search result with its matches highlighted
+	jumpToResult := func(r searchResult, re *regexp.Regexp) {
+		node := revealNode(r.history, r.key)
+		if node == nil {
+			return
+		}
+		viewLog(node, textView, nil, -1)
+		app.SetFocus(textView)
+		updateFocus(textView)
+
+		// Highlight after the tree has reported the new selection, which re-renders the log
+		go app.QueueUpdateDraw(func() {
+			searchRe = re
+			findMatches(re)
+			jumpToMatch(0)
+		})
 	}
 
 	// History list selection handler - use SetSelectedFunc (only fires on Enter)
@@ -392,12 +782,59 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 					historyList.SetCurrentItem(current - 1)
 				}
 				return nil
+			case 'm':
+				// Toggle the comparison base
+				if h := historyMgr.Current(); h == compareBase {
+					compareBase = nil
+				} else {
+					compareBase = h
+				}
+				updateHistoryList()
+				return nil
+			case 'd':
+				// Compare the selected history with the marked one, or with the previous one
+				target := historyMgr.Current()
+				base := compareBase
+				if base == nil || base == target {
+					if historyMgr.CurrentIndex == 0 {
+						textView.SetText("Mark a history with m to compare it with the selected one")
+						return nil
+					}
+					base = historyMgr.Histories[historyMgr.CurrentIndex-1]
+				}
+				closeCompare := func() {
+					pages.RemovePage("compare")
+					app.SetFocus(historyList)
+					updateFocus(historyList)
+				}
+				list := newCompareList(base, target, func(c testChange) {
+					pages.RemovePage("compare")
+					h := target
+					if c.kind == changeRemoved {
+						h = base
+					}
+					if node := revealNode(h, c.key); node != nil {
+						viewLog(node, textView, searchRe, -1)
+					}
+					app.SetFocus(treeView)
+					updateFocus(treeView)
+				}, closeCompare)
+				pages.AddPage("compare", modal(list, 100, 25), true, true)
+				app.SetFocus(list)
+				return nil
 			}
 		}
 		return event
 	})
 
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Let input fields and dialogs receive every key
+		if _, ok := app.GetFocus().(*tview.InputField); ok {
+			return event
+		}
+		if name, _ := pages.GetFrontPage(); name != "main" {
+			return event
+		}
 		if event.Key() == tcell.KeyRune && event.Rune() == 'q' {
 			app.Stop()
 			return nil
@@ -417,26 +854,94 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 			updateFocus(next)
 			return nil
 		}
-		// Export current history with 'e'
-		if event.Key() == tcell.KeyRune && event.Rune() == 'e' {
-			h := historyMgr.Current()
-			if h != nil {
-				// Collect all events from current history
-				h.mu.Lock()
-				var allEvents []collector.TestEvent
-				for _, events := range h.TestCases {
-					allEvents = append(allEvents, events...)
-				}
-				h.mu.Unlock()
-				if len(allEvents) > 0 {
-					filename := fmt.Sprintf("gotestui-export-%s.json", time.Now().Format("20060102-150405"))
-					if err := collector.ExportEvents(filename, allEvents); err != nil {
-						textView.SetText(fmt.Sprintf("Export failed: %v", err))
-					} else {
-						textView.SetText(fmt.Sprintf("Exported to %s (%d events)", filename, len(allEvents)))
+		// Cancel the running go test of the current history with 'c'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'c' {
+			if h := historyMgr.Current(); h != nil && h.Cancel() {
+				updateHistoryList()
+			}
+			return nil
+		}
+		// Export current history with 'e' (go test JSON), 'x' (JUnit XML) or 'H' (HTML report)
+		if event.Key() == tcell.KeyRune {
+			if format, ok := exportFormats[event.Rune()]; ok {
+				h := historyMgr.Current()
+				if h != nil {
+					allEvents := h.allEvents()
+					if len(allEvents) > 0 {
+						filename := fmt.Sprintf("gotestui-export-%s.%s", time.Now().Format("20060102-150405"), format.extension)
+						if err := format.export(filename, allEvents); err != nil {
+							textView.SetText(fmt.Sprintf("Export failed: %v", err))
+						} else {
+							textView.SetText(fmt.Sprintf("Exported to %s (%d events)", filename, len(allEvents)))
+						}
 					}
 				}
+				return nil
+			}
+		}
+		// Search the logs of every test with 'S'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'S' {
+			closeSearch := func() {
+				pages.RemovePage("search")
+				app.SetFocus(treeView)
+				updateFocus(treeView)
 			}
+			panel := newSearchPanel(app, searchOpts, func(re *regexp.Regexp, all bool) []searchResult {
+				histories := historyMgr.Histories
+				if !all {
+					histories = []*History{historyMgr.Current()}
+				}
+				return searchHistories(histories, re)
+			}, func(r searchResult, re *regexp.Regexp, opts searchOptions) {
+				closeSearch()
+				searchOpts = opts
+				searchInput.SetLabel(searchOpts.label())
+				jumpToResult(r, re)
+			}, closeSearch)
+			pages.AddPage("search", modal(panel, 100, 25), true, true)
+			app.SetFocus(panel)
+			return nil
+		}
+		// Show the timeline of the current history with 'T'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'T' {
+			h := historyMgr.Current()
+			if h == nil {
+				return nil
+			}
+			var current collector.TestKey
+			if ref, ok := treeView.GetCurrentNode().GetReference().(*nodeRef); ok {
+				current = ref.key
+			}
+			closeTimeline := func() {
+				pages.RemovePage("timeline")
+				app.SetFocus(treeView)
+				updateFocus(treeView)
+			}
+			panel := newTimelinePanel(h, current, func(key collector.TestKey) {
+				closeTimeline()
+				if node := revealNode(h, key); node != nil {
+					viewLog(node, textView, searchRe, -1)
+				}
+			}, closeTimeline)
+			pages.AddPage("timeline", panel, true, true)
+			app.SetFocus(panel)
+			return nil
+		}
+		// List the flaky tests of all histories with 'y'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'y' {
+			closeFlaky := func() {
+				pages.RemovePage("flaky")
+				app.SetFocus(treeView)
+				updateFocus(treeView)
+			}
+			list := newFlakyList(flakyTests, func(f flakyTest) {
+				closeFlaky()
+				if node := revealNode(f.lastFailure, f.key); node != nil {
+					viewLog(node, textView, searchRe, -1)
+				}
+			}, closeFlaky)
+			pages.AddPage("flaky", modal(list, 100, 20), true, true)
+			app.SetFocus(list)
 			return nil
 		}
 		// Escape to go back to tree view
@@ -448,6 +953,122 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 		return event
 	})
 
+	// Start a rerun in a new history and switch to it
+	startRerun := func(rerunTarget *rerunTarget) *History {
+		ctx, cancel := context.WithCancel(runCtx)
+		historyName := rerunTarget.historyName
+		if options := rerunTarget.args.OptionString(); options != "" {
+			historyName += " [" + options + "]"
+		}
+		rerunHistory := historyMgr.AddHistory(historyName)
+		rerunHistory.State = StateRunning
+		rerunHistory.Args = rerunTarget.args
+		rerunHistory.cancel = cancel
+		rerunHistory.stress = rerunTarget.stress
+		rerunHistory.partial = rerunTarget.partial
+		treeView.SetRoot(rerunHistory.Root).SetCurrentNode(rerunHistory.Root)
+		updateHistoryList()
+
+		rerunChan := make(chan collector.TestEvent, 100)
+		var runErr error
+
+		// Process events for this rerun
+		go func() {
+			for te := range rerunChan {
+				key, eventsCopy, ok := rerunHistory.addEvent(te)
+				if !ok {
+					continue
+				}
+				app.QueueUpdateDraw(func() {
+					updateNode(rerunHistory, key, eventsCopy, spinnerFrames[spinnerFrame.Load()])
+					// The tree filter is reapplied by the animation ticker while the run goes on
+					if historyMgr.Current() == rerunHistory {
+						viewLog(treeView.GetCurrentNode(), textView, searchRe, -1)
+					}
+				})
+			}
+
+			// rerunChan is closed after runErr is set
+			rerunHistory.finish()
+			app.QueueUpdateDraw(func() {
+				updateHistoryList()
+				refreshFlaky()
+				if historyMgr.Current() == rerunHistory && treeFilter.Active() {
+					applyFilter(rerunHistory, treeFilter)
+				}
+				if opts.SelectFailure && historyMgr.Current() == rerunHistory {
+					selectFailure(false, true)
+				}
+				if runErr != nil && !errors.Is(runErr, context.Canceled) {
+					textView.SetText(fmt.Sprintf("Rerun failed: %v", runErr))
+				}
+			})
+		}()
+
+		// Run the test
+		runs.Add(1)
+		go func() {
+			defer runs.Done()
+			defer cancel()
+			runErr = rerunTarget.run(ctx, rerunChan)
+			close(rerunChan)
+		}()
+		return rerunHistory
+	}
+
+	// Watch mode reruns the affected packages whenever .go files change,
+	// cancelling the previous watch run if it is still going
+	if opts.Watch && opts.TestArgs != nil {
+		historyList.SetTitle("History (watching)")
+		go func() {
+			showError := func(err error) {
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+				app.QueueUpdateDraw(func() {
+					textView.SetText(fmt.Sprintf("Watch failed: %v", err))
+				})
+			}
+
+			root, err := collector.ModuleRoot(runCtx, *opts.TestArgs)
+			if err != nil {
+				showError(err)
+				return
+			}
+			changes := make(chan []string)
+			go func() {
+				if err := collector.Watch(runCtx, root, changes); err != nil {
+					showError(err)
+				}
+			}()
+
+			var watchHistory *History
+			for {
+				var dirs []string
+				select {
+				case dirs = <-changes:
+				case <-runCtx.Done():
+					return
+				}
+				pkgs, err := collector.AffectedPackages(runCtx, *opts.TestArgs, dirs)
+				if err != nil {
+					showError(err)
+					continue
+				}
+				if len(pkgs) == 0 {
+					continue
+				}
+				target := newWatchTarget(opts.TestArgs, pkgs)
+				app.QueueUpdateDraw(func() {
+					if watchHistory != nil {
+						watchHistory.Cancel()
+					}
+					watchHistory = startRerun(target)
+				})
+			}
+		}()
+	}
+
 	treeView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		currentNode := treeView.GetCurrentNode()
 		if currentNode == nil {
@@ -470,79 +1091,185 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 
 		// Rerun test with 'r' - creates a new history
 		if event.Key() == tcell.KeyRune && event.Rune() == 'r' {
-			rerunTarget := parseRerunTarget(currentNode.GetReference())
-			if rerunTarget == nil {
+			h := historyMgr.Current()
+			if h == nil {
 				return nil
 			}
+			if rerunTarget := newRerunTarget(h, currentNode, h.Args); rerunTarget != nil {
+				startRerun(rerunTarget)
+			}
+			return nil
+		}
 
-			rerunHistory := historyMgr.AddHistory(rerunTarget.historyName)
-			rerunHistory.State = StateRunning
-			treeView.SetRoot(rerunHistory.Root).SetCurrentNode(rerunHistory.Root)
-			updateHistoryList()
+		// Rerun test with modified flags and environment with 'R'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'R' {
+			h := historyMgr.Current()
+			if h == nil {
+				return nil
+			}
+			target := newRerunTarget(h, currentNode, h.Args)
+			if target == nil {
+				return nil
+			}
+			var base collector.TestArgs
+			if h.Args != nil {
+				base = h.Args.Clone()
+			}
+			name := strings.TrimPrefix(target.historyName, "Rerun: ")
+			closeDialog := func() {
+				pages.RemovePage("rerun")
+				app.SetFocus(treeView)
+			}
+			form := newRerunForm(name, base, func(args collector.TestArgs) {
+				closeDialog()
+				if rerunTarget := newRerunTarget(h, currentNode, &args); rerunTarget != nil {
+					startRerun(rerunTarget)
+				}
+			}, closeDialog)
+			pages.AddPage("rerun", modal(form, 70, 23), true, true)
+			app.SetFocus(form)
+			return nil
+		}
 
-			rerunChan := make(chan collector.TestEvent, 100)
+		// Stress the selected test with 's', or show the tally of the current stress run
+		if event.Key() == tcell.KeyRune && event.Rune() == 's' {
+			h := historyMgr.Current()
+			if h == nil {
+				return nil
+			}
+			showStress := func(h *History) {
+				panel := newStressPanel(app, h, func() {
+					pages.RemovePage("stress")
+					app.SetFocus(treeView)
+					updateFocus(treeView)
+				})
+				pages.AddPage("stress", modal(panel, 120, 30), true, true)
+				app.SetFocus(panel)
+			}
+			if h.stress != nil {
+				showStress(h)
+				return nil
+			}
+			ref, ok := currentNode.GetReference().(*nodeRef)
+			if !ok || ref.key.Test == "" {
+				textView.SetText("Select a test to stress")
+				return nil
+			}
+			closeDialog := func() {
+				pages.RemovePage("stress")
+				app.SetFocus(treeView)
+			}
+			form := newStressForm(lastPathComponent(ref.key.Test), func(stressOpts collector.StressOptions) {
+				closeDialog()
+				showStress(startRerun(newStressTarget(ref.key, h.Args, stressOpts)))
+			}, closeDialog)
+			pages.AddPage("stress", modal(form, 60, 11), true, true)
+			app.SetFocus(form)
+			return nil
+		}
 
-			// Process events for this rerun
-			go func() {
-				for te := range rerunChan {
-					if te.IsRootEvent() {
-						continue
+		// Jump to the next or previous failure with ']' and '['
+		if event.Key() == tcell.KeyRune && (event.Rune() == ']' || event.Rune() == '[') {
+			selectFailure(event.Rune() == '[', false)
+			return nil
+		}
+
+		// Cycle the status filter with 'f'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'f' {
+			treeFilter.Status = treeFilter.Status.Next()
+			refreshFilter()
+			return nil
+		}
+
+		// Filter the tree by name with '/'
+		if event.Key() == tcell.KeyRune && event.Rune() == '/' {
+			if !filterMode {
+				filterMode = true
+				leftPanel.AddItem(filterInput, 1, 0, false)
+			}
+			filterInput.SetText(treeFilter.Name)
+			app.SetFocus(filterInput)
+			return nil
+		}
+
+		// Rerun every failed test of the current history with 'F'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'F' {
+			h := historyMgr.Current()
+			if h == nil {
+				return nil
+			}
+			if rerunTarget := parseRerunFailed(h); rerunTarget != nil {
+				startRerun(rerunTarget)
+			}
+			return nil
+		}
+
+		// Diff the log with the same test in the comparison base, or in the nearest history running it, with 'D'
+		if event.Key() == tcell.KeyRune && event.Rune() == 'D' {
+			ref, ok := treeView.GetCurrentNode().GetReference().(*nodeRef)
+			current := historyMgr.Current()
+			if !ok || current == nil {
+				return nil
+			}
+			key := nodeKey(ref.key)
+			otherIndex := -1
+			for i, h := range historyMgr.Histories {
+				if h == compareBase && i != historyMgr.CurrentIndex && h.NodeMap[key] != nil {
+					otherIndex = i
+				}
+			}
+			for d := 1; otherIndex < 0 && d < len(historyMgr.Histories); d++ {
+				for _, i := range []int{historyMgr.CurrentIndex - d, historyMgr.CurrentIndex + d} {
+					if i >= 0 && i < len(historyMgr.Histories) && historyMgr.Histories[i].NodeMap[key] != nil {
+						otherIndex = i
+						break
 					}
-					rerunHistory.mu.Lock()
-					rerunHistory.TestCases[te.Test] = append(rerunHistory.TestCases[te.Test], te)
-					testName := te.Test
-					eventsCopy := make([]collector.TestEvent, len(rerunHistory.TestCases[te.Test]))
-					copy(eventsCopy, rerunHistory.TestCases[te.Test])
-					rerunHistory.mu.Unlock()
-					app.QueueUpdateDraw(func() {
-						updateNode(rerunHistory.Root, rerunHistory.NodeMap, testName, eventsCopy, spinnerFrames[spinnerFrame.Load()])
-						if historyMgr.Current() == rerunHistory {
-							viewLog(treeView.GetCurrentNode(), textView, searchQuery, -1)
-						}
-					})
 				}
-			}()
+			}
+			if otherIndex < 0 {
+				textView.SetText("No other history ran this test")
+				return nil
+			}
 
-			// Run the test
-			go func() {
-				defer close(rerunChan)
-				defer func() {
-					rerunHistory.mu.Lock()
-					rerunHistory.State = stateFromTestResult(rerunHistory.TestCases)
-					rerunHistory.mu.Unlock()
-					app.QueueUpdateDraw(updateHistoryList)
-				}()
-				if err := rerunTarget.run(rerunChan); err != nil {
-					app.QueueUpdateDraw(func() {
-						textView.SetText(fmt.Sprintf("Rerun failed: %v", err))
-					})
-				}
-			}()
+			older, newer := historyMgr.Histories[otherIndex], current
+			if otherIndex > historyMgr.CurrentIndex {
+				older, newer = newer, older
+			}
+			name := ref.key.Test
+			if name == "" {
+				name = ref.key.Package
+			}
+			panel := newLogDiffPanel(app, name,
+				older.Name, nodeOutput(older.NodeMap[key]),
+				newer.Name, nodeOutput(newer.NodeMap[key]),
+				func() {
+					pages.RemovePage("logdiff")
+					app.SetFocus(treeView)
+					updateFocus(treeView)
+				})
+			pages.AddPage("logdiff", panel, true, true)
+			app.SetFocus(panel)
 			return nil
 		}
 		return event
 	})
 
 	treeView.SetChangedFunc(func(node *tview.TreeNode) {
-		viewLog(node, textView, searchQuery, -1)
+		viewLog(node, textView, searchRe, -1)
 	})
 
 	// Process initial events
 	go func() {
 		processEvent := func(te collector.TestEvent) {
-			if te.IsRootEvent() {
+			key, events, ok := initialHistory.addEvent(te)
+			if !ok {
 				return
 			}
-			initialHistory.mu.Lock()
-			initialHistory.TestCases[te.Test] = append(initialHistory.TestCases[te.Test], te)
-			testName := te.Test
-			events := make([]collector.TestEvent, len(initialHistory.TestCases[te.Test]))
-			copy(events, initialHistory.TestCases[te.Test])
-			initialHistory.mu.Unlock()
 			app.QueueUpdateDraw(func() {
-				updateNode(initialHistory.Root, initialHistory.NodeMap, testName, events, spinnerFrames[spinnerFrame.Load()])
+				updateNode(initialHistory, key, events, spinnerFrames[spinnerFrame.Load()])
+				// The tree filter is reapplied by the animation ticker while the run goes on
 				if historyMgr.Current() == initialHistory {
-					viewLog(treeView.GetCurrentNode(), textView, searchQuery, -1)
+					viewLog(treeView.GetCurrentNode(), textView, searchRe, -1)
 				}
 			})
 		}
@@ -558,10 +1285,17 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 					case te := <-eventChan:
 						processEvent(te)
 					default:
-						initialHistory.mu.Lock()
-						initialHistory.State = stateFromTestResult(initialHistory.TestCases)
-						initialHistory.mu.Unlock()
-						app.QueueUpdateDraw(updateHistoryList)
+						initialHistory.finish()
+						app.QueueUpdateDraw(func() {
+							updateHistoryList()
+							refreshFlaky()
+							if historyMgr.Current() == initialHistory && treeFilter.Active() {
+								applyFilter(initialHistory, treeFilter)
+							}
+							if opts.SelectFailure && historyMgr.Current() == initialHistory {
+								selectFailure(false, true)
+							}
+						})
 						return
 					}
 				}
@@ -569,9 +1303,8 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 		}
 	}()
 
-	// Layout: Left panel (History + Tests), Right panel (Log)
-	leftPanel := tview.NewFlex().SetDirection(tview.FlexRow).
-		AddItem(historyList, 0, 1, false).
+	// Layout: Left panel (History + Tests + filter input), Right panel (Log)
+	leftPanel.AddItem(historyList, 0, 1, false).
 		AddItem(treeView, 0, 3, true)
 
 	mainFlex := tview.NewFlex().
@@ -583,48 +1316,247 @@ func CreateApplication(eventChan <-chan collector.TestEvent, doneChan <-chan str
 		AddItem(mainFlex, 0, 1, true).
 		AddItem(footer, 1, 0, false)
 
-	if err := app.SetRoot(appFlex, true).Run(); err != nil {
+	pages.AddPage("main", appFlex, true, true)
+	err := app.SetRoot(pages, true).Run()
+
+	// Stop every rerun and wait for its processes to exit
+	cancelRuns()
+	runs.Wait()
+
+	if err != nil {
 		panic(err)
 	}
 }
 
+// Key usage shown in the footer for each panel
+const (
+	historyUsage = "q: quit, Tab: focus, j/k: select, Enter: open, m: mark base, d: compare, c: cancel, e/x/H: export json/junit/html, S: search all logs, T: timeline, y: flaky tests"
+	treeUsage    = "q: quit, Tab: focus, Space: expand, Enter: log, r/R: rerun (with flags), F: rerun failed, s: stress, D: diff log, ]/[: next/prev failure, f: status filter, /: name filter, c: cancel, e/x/H: export, S: search all logs, T: timeline, y: flaky tests"
+	logUsage     = "q: quit, Tab: focus, Enter: tree, j/k/g/G: scroll, /: search (Ctrl-R: regex, Ctrl-T: case), n/N: next/prev, l/L: file locations, o: open in editor, Esc: clear search, S: search all logs, T: timeline, y: flaky tests"
+)
+
+// exportFormat describes a file format a history can be exported to
+type exportFormat struct {
+	extension string
+	export    func(filename string, events []collector.TestEvent) error
+}
+
+// exportFormats maps export keys to their formats
+var exportFormats = map[rune]exportFormat{
+	'e': {extension: "json", export: collector.ExportEvents},
+	'x': {extension: "xml", export: collector.ExportJUnit},
+	'H': {extension: "html", export: collector.ExportHTML},
+}
+
 // rerunTarget holds information needed to rerun a test or package
 type rerunTarget struct {
 	historyName string
-	run         func(chan<- collector.TestEvent) error
+	args        *collector.TestArgs
+	run         func(context.Context, chan<- collector.TestEvent) error
+	stress      *stressRun      // Set for stress runs
+	partial     map[string]bool // Packages of which only some tests run
+}
+
+// newRerunTarget returns the rerun target for a node of the history, run with the given arguments
+func newRerunTarget(h *History, node *tview.TreeNode, args *collector.TestArgs) *rerunTarget {
+	if node == h.Root {
+		// Repeating the whole run needs the original packages
+		if h.Args == nil {
+			return nil
+		}
+		return parseRerunAll(args)
+	}
+	return parseRerunTarget(node.GetReference(), args)
 }
 
-// parseRerunTarget extracts rerun information from a node reference
-func parseRerunTarget(ref interface{}) *rerunTarget {
+// parseRerunAll returns a target that repeats the whole go test invocation of a history
+func parseRerunAll(args *collector.TestArgs) *rerunTarget {
+	if args == nil {
+		return nil
+	}
+	runArgs := args.Clone()
+	return &rerunTarget{
+		historyName: "Rerun: all",
+		args:        &runArgs,
+		run: func(ctx context.Context, ch chan<- collector.TestEvent) error {
+			return collector.Run(ctx, runArgs, ch)
+		},
+	}
+}
+
+// newWatchTarget returns a target that runs the given packages in a single go test
+// with the arguments of the watched run
+func newWatchTarget(args *collector.TestArgs, pkgs []string) *rerunTarget {
+	runArgs := args.Clone()
+	runArgs.Packages = pkgs
+
+	names := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		names[i] = lastPathComponent(pkg)
+	}
+	historyName := "Watch: " + strings.Join(names, ", ")
+	if len(names) > 3 {
+		historyName = fmt.Sprintf("Watch: %s, ... (%d packages)", strings.Join(names[:3], ", "), len(names))
+	}
+
+	return &rerunTarget{
+		historyName: historyName,
+		args:        &runArgs,
+		run: func(ctx context.Context, ch chan<- collector.TestEvent) error {
+			return collector.Run(ctx, runArgs, ch)
+		},
+	}
+}
+
+// parseRerunFailed returns a target that reruns every test of a history that failed on its own account,
+// running one go test per package
+func parseRerunFailed(h *History) *rerunTarget {
+	h.mu.Lock()
+	failed := failedTests(h.TestCases)
+	h.mu.Unlock()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	var base collector.TestArgs
+	if h.Args != nil {
+		base = h.Args.Clone()
+	}
+	base.Packages = nil
+
+	count := 0
+	partial := make(map[string]bool)
+	for _, pkg := range sortedKeys(failed) {
+		base.Packages = append(base.Packages, pkg)
+		count += len(failed[pkg])
+		partial[pkg] = true
+	}
+
+	return &rerunTarget{
+		historyName: fmt.Sprintf("Rerun: failed (%d)", count),
+		args:        &base,
+		partial:     partial,
+		run: func(ctx context.Context, ch chan<- collector.TestEvent) error {
+			for _, pkg := range base.Packages {
+				if err := collector.RunTests(ctx, pkg, failed[pkg], base, ch); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// failedTests returns the tests that failed on their own account, i.e. failed tests
+// none of whose subtests failed, grouped by package
+func failedTests(testCases TestCaseMap) map[string][]string {
+	hasFailedSubtest := make(map[collector.TestKey]bool)
+	for key, events := range testCases {
+		if !hasFailedEvent(events) {
+			continue
+		}
+		for i := strings.LastIndex(key.Test, "/"); i >= 0; i = strings.LastIndex(key.Test[:i], "/") {
+			hasFailedSubtest[collector.TestKey{Package: key.Package, Test: key.Test[:i]}] = true
+		}
+	}
+
+	failed := make(map[string][]string)
+	for key, events := range testCases {
+		if key.Test == "" || hasFailedSubtest[key] || !hasFailedEvent(events) {
+			continue
+		}
+		failed[key.Package] = append(failed[key.Package], key.Test)
+	}
+	for _, tests := range failed {
+		sort.Strings(tests)
+	}
+	return failed
+}
+
+// sortedKeys returns the keys of a map in ascending order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseRerunTarget extracts rerun information from a node reference.
+// Reruns keep the flags of the history the node belongs to.
+func parseRerunTarget(ref interface{}, args *collector.TestArgs) *rerunTarget {
 	if ref == nil {
 		return nil
 	}
 
-	switch v := ref.(type) {
-	case string:
+	var base collector.TestArgs
+	if args != nil {
+		base = args.Clone()
+	}
+
+	v, ok := ref.(*nodeRef)
+	if !ok {
+		return nil
+	}
+
+	pkg := v.key.Package
+	if v.key.Test == "" {
 		// Package node
-		pkg := v
+		base.Packages = []string{pkg}
 		return &rerunTarget{
 			historyName: fmt.Sprintf("Rerun: pkg %s", lastPathComponent(pkg)),
-			run: func(ch chan<- collector.TestEvent) error {
-				return collector.RunPackage(pkg, ch)
+			args:        &base,
+			run: func(ctx context.Context, ch chan<- collector.TestEvent) error {
+				return collector.RunPackage(ctx, pkg, base, ch)
 			},
 		}
-	case []collector.TestEvent:
-		// Test node
-		if len(v) == 0 {
-			return nil
-		}
-		pkg := v[0].Package
-		testName := v[0].Test
-		return &rerunTarget{
-			historyName: fmt.Sprintf("Rerun: %s", lastPathComponent(testName)),
-			run: func(ch chan<- collector.TestEvent) error {
-				return collector.RunTest(pkg, testName, ch)
-			},
-		}
-	default:
-		return nil
+	}
+
+	// Test node
+	testName := v.key.Test
+	base.Packages = []string{pkg}
+	return &rerunTarget{
+		historyName: fmt.Sprintf("Rerun: %s", lastPathComponent(testName)),
+		args:        &base,
+		partial:     map[string]bool{pkg: true},
+		run: func(ctx context.Context, ch chan<- collector.TestEvent) error {
+			return collector.RunTest(ctx, pkg, testName, base, ch)
+		},
+	}
+}
+
+// newStressTarget returns a target that runs a test repeatedly, tallying its iterations
+func newStressTarget(key collector.TestKey, args *collector.TestArgs, opts collector.StressOptions) *rerunTarget {
+	var base collector.TestArgs
+	if args != nil {
+		base = args.Clone()
+	}
+	base.Packages = []string{key.Package}
+
+	historyName := fmt.Sprintf("Stress: %s ×%d", lastPathComponent(key.Test), opts.Iterations)
+	if opts.UntilFailure {
+		historyName += " until failure"
+	}
+	stress := newStressRun(key, opts)
+	return &rerunTarget{
+		historyName: historyName,
+		args:        &base,
+		stress:      stress,
+		partial:     map[string]bool{key.Package: true},
+		run: func(ctx context.Context, ch chan<- collector.TestEvent) error {
+			events := make(chan collector.StressEvent, 100)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- collector.RunStress(ctx, key.Package, key.Test, base, opts, events)
+				close(events)
+			}()
+			for e := range events {
+				stress.record(e)
+				collector.Send(ctx, ch, e.Event)
+			}
+			return <-errc
+		},
 	}
 }
 
@@ -636,55 +1568,152 @@ func lastPathComponent(path string) string {
 	return path
 }
 
-func updateNode(root *tview.TreeNode, nodeMap map[string]*tview.TreeNode, testName string, events []collector.TestEvent, spinnerIcon string) {
+// nodeRef is the reference attached to package, test and attempt nodes
+type nodeRef struct {
+	key     collector.TestKey
+	events  []collector.TestEvent
+	attempt int // Number of the attempt, from 1, for the nodes of single attempts of a test
+}
+
+// nodeKey returns the NodeMap key of a package (empty test name) or test node
+func nodeKey(key collector.TestKey) string {
+	if key.Test == "" {
+		return "pkg:" + key.Package
+	}
+	return key.Package + ":" + key.Test
+}
+
+// updateNode creates or updates the tree node of a test or package of the history
+func updateNode(h *History, key collector.TestKey, events []collector.TestEvent, spinnerIcon string) {
 	if len(events) == 0 {
 		return
 	}
+	nodeMap := h.NodeMap
 
 	// Get package name and create package node
-	pkg := events[0].Package
-	pkgNode, exists := nodeMap["pkg:"+pkg]
+	pkg := key.Package
+	pkgKey := nodeKey(collector.TestKey{Package: pkg})
+	pkgNode, exists := nodeMap[pkgKey]
 	if !exists {
 		pkgNode = tview.NewTreeNode("📦 " + lastPathComponent(pkg))
 		pkgNode.SetExpanded(true)
 		pkgNode.SetColor(tcell.ColorBlue)
-		pkgNode.SetReference(pkg) // Store full package path for rerun
-		nodeMap["pkg:"+pkg] = pkgNode
-		root.AddChild(pkgNode)
+		pkgNode.SetReference(&nodeRef{key: collector.TestKey{Package: pkg}}) // Store full package path for rerun
+		nodeMap[pkgKey] = pkgNode
+		h.addChild(h.Root, pkgNode)
+	}
+
+	// Package-level events update the package node itself
+	if key.Test == "" {
+		pkgNode.SetReference(&nodeRef{key: key, events: events})
+		renderNode(h, pkgNode, spinnerIcon)
+		return
 	}
 
 	// Build test hierarchy under package node
-	parts := strings.Split(testName, "/")
+	parts := strings.Split(key.Test, "/")
 	parent := pkgNode
+	ancestors := make([]*tview.TreeNode, 0, len(parts))
 
 	for i, part := range parts {
-		path := pkg + ":" + strings.Join(parts[:i+1], "/")
+		ancestors = append(ancestors, parent)
+		partKey := collector.TestKey{Package: pkg, Test: strings.Join(parts[:i+1], "/")}
+		path := nodeKey(partKey)
 		node, exists := nodeMap[path]
 		if !exists {
 			node = tview.NewTreeNode(part)
 			node.SetExpanded(true)
+			node.SetReference(&nodeRef{key: partKey})
 			nodeMap[path] = node
-			parent.AddChild(node)
+			h.addChild(parent, node)
 		}
 		parent = node
 	}
 
-	statusIcon, color, elapsed := resolveTestStatus(events, spinnerIcon)
+	parent.SetReference(&nodeRef{key: key, events: events})
+	h.updateAttempts(parent, key, events, spinnerIcon)
+	renderNode(h, parent, spinnerIcon)
+
+	// Propagate the new status from the innermost parent test up to the package node
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		h.updateCounts(ancestors[i])
+		renderNode(h, ancestors[i], spinnerIcon)
+	}
+}
+
+// renderNode sets the text and color of a node from its own events and,
+// for packages and tests with subtests, the counts of the leaf tests below it
+func renderNode(h *History, node *tview.TreeNode, spinnerIcon string) {
+	ref, ok := node.GetReference().(*nodeRef)
+	if !ok {
+		return
+	}
+	if ref.attempt > 0 {
+		statusIcon, color, elapsed := resolveTestStatus(ref.events, spinnerIcon)
+		node.SetText(formatAttemptText(ref.attempt, statusIcon, elapsed)).SetColor(color)
+		return
+	}
+
+	counts, hasCounts := h.counts[node]
+	if attempts := collector.SplitAttempts(ref.events); !hasCounts && ref.key.Test != "" && len(attempts) > 1 {
+		counts, hasCounts = attemptCounts(attempts), true
+	}
+	countText := ""
+	if hasCounts {
+		countText = counts.String()
+	}
 
-	parent.SetReference(events)
+	if ref.key.Test == "" {
+		statusIcon, color, elapsed, note := resolvePackageStatus(ref.events, spinnerIcon)
+		if hasCounts {
+			color = counts.color(color)
+		}
+		text := formatPackageText(getExpandIcon(node), statusIcon, lastPathComponent(ref.key.Package), countText, note, elapsed)
+		node.SetText(text).SetColor(color)
+		return
+	}
 
-	testDisplayName := parts[len(parts)-1]
-	expandIcon := getExpandIcon(parent)
-	text := formatNodeText(expandIcon, statusIcon, testDisplayName, elapsed)
-	parent.SetText(text).SetColor(color)
+	statusIcon, color, elapsed := resolveTestStatus(ref.events, spinnerIcon)
+	if hasCounts {
+		color = counts.color(color)
+		// A test failing in an earlier attempt is not shown as passed
+		if counts.failed > 0 && counts.running == 0 {
+			statusIcon = "✗"
+		}
+	}
+	name := lastPathComponent(ref.key.Test)
+	if h.flaky[ref.key] {
+		name += " " + flakyIcon
+	}
+	text := formatNodeText(getExpandIcon(node), statusIcon, name, countText, elapsed)
+	node.SetText(text).SetColor(color)
 }
 
 // formatNodeText formats the display text for a tree node
-func formatNodeText(expandIcon, statusIcon, name string, elapsed float64) string {
+func formatNodeText(expandIcon, statusIcon, name, counts string, elapsed float64) string {
+	text := fmt.Sprintf("%s%s %s", expandIcon, statusIcon, name)
+	if counts != "" {
+		text += " " + counts
+	}
+	if elapsed > 0 {
+		text += fmt.Sprintf(" [%.3fs]", elapsed)
+	}
+	return text
+}
+
+// formatPackageText formats the display text for a package node
+func formatPackageText(expandIcon, statusIcon, name, counts, note string, elapsed float64) string {
+	text := fmt.Sprintf("%s📦 %s %s", expandIcon, name, statusIcon)
+	if counts != "" {
+		text += " " + counts
+	}
+	if note != "" {
+		text += " (" + note + ")"
+	}
 	if elapsed > 0 {
-		return fmt.Sprintf("%s%s %s [%.3fs]", expandIcon, statusIcon, name, elapsed)
+		text += fmt.Sprintf(" [%.3fs]", elapsed)
 	}
-	return fmt.Sprintf("%s%s %s", expandIcon, statusIcon, name)
+	return text
 }
 
 // getExpandIcon returns the expand/collapse icon for a node based on its state
@@ -716,113 +1745,28 @@ func updateNodeExpandIcon(node *tview.TreeNode) {
 }
 
 func getTestEvent(node *tview.TreeNode) []collector.TestEvent {
-	events, _ := node.GetReference().([]collector.TestEvent)
-	return events
+	ref, _ := node.GetReference().(*nodeRef)
+	if ref == nil {
+		return nil
+	}
+	return ref.events
 }
 
-func viewLog(node *tview.TreeNode, textView *tview.TextView, searchQuery string, currentMatch int) {
+func viewLog(node *tview.TreeNode, textView *tview.TextView, search *regexp.Regexp, currentMatch int) {
 	if node == nil || node.GetText() == "." {
 		textView.SetText("select testcase")
 		return
 	}
+	textView.SetText(renderLog(parseANSI(nodeOutput(node)), search, currentMatch))
+}
 
+// nodeOutput returns the output of a node's events, including ANSI escape sequences
+func nodeOutput(node *tview.TreeNode) string {
 	var builder strings.Builder
 	for _, event := range getTestEvent(node) {
 		builder.WriteString(event.Output)
 	}
-
-	text := builder.String()
-	if searchQuery != "" {
-		text = highlightMatches(text, searchQuery, currentMatch)
-	}
-	textView.SetText(text)
-}
-
-// highlightMatches highlights all occurrences of query in text (case-insensitive)
-// currentMatch indicates which match (0-indexed) should be highlighted as current (-1 for none)
-func highlightMatches(text, query string, currentMatch int) string {
-	if query == "" {
-		return text
-	}
-
-	// Use case-insensitive search with proper UTF-8 handling
-	lowerText := strings.ToLower(text)
-	lowerQuery := strings.ToLower(query)
-	queryLen := len(lowerQuery)
-
-	var result strings.Builder
-	lastEnd := 0
-	matchIndex := 0
-	textPos := 0 // Position in original text
-
-	for {
-		idx := strings.Index(lowerText[lastEnd:], lowerQuery)
-		if idx == -1 {
-			result.WriteString(text[textPos:])
-			break
-		}
-
-		// Find corresponding position in original text
-		// by counting the same number of bytes in lowercased text
-		matchStartLower := lastEnd + idx
-		matchEndLower := matchStartLower + queryLen
-
-		// Map positions from lowercased to original text
-		origStart := mapLowerToOrigPos(text, lowerText, matchStartLower)
-		origEnd := mapLowerToOrigPos(text, lowerText, matchEndLower)
-
-		// Add text before match
-		result.WriteString(text[textPos:origStart])
-
-		// Add highlighted match
-		if matchIndex == currentMatch {
-			// Current match: orange background (vim-like IncSearch)
-			result.WriteString("[#000000:#ff8800:b]")
-		} else {
-			// Other matches: yellow background (vim-like Search)
-			result.WriteString("[#000000:#ffff00:b]")
-		}
-		result.WriteString(text[origStart:origEnd])
-		result.WriteString("[-:-:-]")
-
-		lastEnd = matchEndLower
-		textPos = origEnd
-		matchIndex++
-	}
-
-	return result.String()
-}
-
-// mapLowerToOrigPos maps a byte position in lowercased text to the original text
-// This handles cases where lowercasing changes byte length (e.g., some Unicode chars)
-func mapLowerToOrigPos(orig, lower string, lowerPos int) int {
-	if lowerPos <= 0 {
-		return 0
-	}
-	if lowerPos >= len(lower) {
-		return len(orig)
-	}
-
-	// For ASCII-only text (common case), positions are the same
-	// Check if we can use fast path
-	if len(orig) == len(lower) {
-		return lowerPos
-	}
-
-	// Slow path: iterate through runes to find correct position
-	origPos := 0
-	lowerIdx := 0
-	for _, r := range orig {
-		if lowerIdx >= lowerPos {
-			break
-		}
-		lowerR := []rune(strings.ToLower(string(r)))
-		for _, lr := range lowerR {
-			lowerIdx += len(string(lr))
-		}
-		origPos += len(string(r))
-	}
-	return origPos
+	return builder.String()
 }
 
 // resolveTestStatus determines the status icon, color, and elapsed time from test events
@@ -835,15 +1779,18 @@ func resolveTestStatus(events []collector.TestEvent, spinnerIcon string) (status
 			elapsed = te.Elapsed
 		}
 		switch te.Action {
-		case collector.ActionPass:
+		case collector.ActionPass, collector.ActionBench:
 			statusIcon = "✓"
 			color = tcell.ColorGreen
 		case collector.ActionFail:
 			statusIcon = "✗"
 			color = tcell.ColorRed
-		case collector.ActionRun:
+		case collector.ActionRun, collector.ActionCont:
 			statusIcon = spinnerIcon
 			color = tcell.ColorYellow
+		case collector.ActionPause:
+			statusIcon = "⏸"
+			color = tcell.ColorOrange
 		case collector.ActionSkip:
 			statusIcon = "⏭"
 			color = tcell.ColorDarkCyan
@@ -855,13 +1802,69 @@ func resolveTestStatus(events []collector.TestEvent, spinnerIcon string) (status
 	return statusIcon, color, elapsed
 }
 
-// isTestRunning checks if the test is still running based on the last terminal action
+// resolvePackageStatus determines the status icon, color, elapsed time and a short note
+// (build failure, cached result, coverage) from package-level events
+func resolvePackageStatus(events []collector.TestEvent, spinnerIcon string) (statusIcon string, color tcell.Color, elapsed float64, note string) {
+	statusIcon = "⧗"
+	color = tcell.ColorBlue
+
+	for _, te := range events {
+		if te.Elapsed > 0 {
+			elapsed = te.Elapsed
+		}
+		switch te.Action {
+		case collector.ActionStart:
+			statusIcon = spinnerIcon
+			color = tcell.ColorYellow
+		case collector.ActionPass:
+			statusIcon = "✓"
+			color = tcell.ColorGreen
+		case collector.ActionFail:
+			statusIcon = "✗"
+			color = tcell.ColorRed
+			if te.FailedBuild != "" {
+				note = "build failed"
+			}
+		case collector.ActionSkip:
+			statusIcon = "⏭"
+			color = tcell.ColorDarkCyan
+		case collector.ActionOutput:
+			if n := packageOutputNote(te.Output); n != "" {
+				note = n
+			}
+		}
+	}
+	return statusIcon, color, elapsed, note
+}
+
+// packageOutputNote extracts a short note from a line of package-level output
+func packageOutputNote(output string) string {
+	switch {
+	case strings.Contains(output, "[build failed]"):
+		return "build failed"
+	case strings.Contains(output, "[setup failed]"):
+		return "setup failed"
+	case strings.Contains(output, "[no test files]"):
+		return "no test files"
+	case strings.Contains(output, "(cached)"):
+		return "cached"
+	}
+	if idx := strings.Index(output, "coverage: "); idx >= 0 {
+		coverage := strings.TrimSpace(output[idx:])
+		return strings.TrimSuffix(coverage, " of statements")
+	}
+	return ""
+}
+
+// isTestRunning checks if the test is still running (or paused) based on the last terminal action
 func isTestRunning(events []collector.TestEvent) bool {
 	for i := len(events) - 1; i >= 0; i-- {
-		switch events[i].Action {
-		case collector.ActionPass, collector.ActionFail, collector.ActionSkip:
+		action := events[i].Action
+		if action.IsTerminal() {
 			return false
-		case collector.ActionRun:
+		}
+		switch action {
+		case collector.ActionRun, collector.ActionStart, collector.ActionPause, collector.ActionCont:
 			return true
 		}
 	}
@@ -871,10 +1874,18 @@ func isTestRunning(events []collector.TestEvent) bool {
 // hasFailedTest checks if any test in the test cases has failed
 func hasFailedTest(testCases TestCaseMap) bool {
 	for _, events := range testCases {
-		for _, te := range events {
-			if te.Action == collector.ActionFail {
-				return true
-			}
+		if hasFailedEvent(events) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFailedEvent checks if any of the events is a failure
+func hasFailedEvent(events []collector.TestEvent) bool {
+	for _, te := range events {
+		if te.Action == collector.ActionFail {
+			return true
 		}
 	}
 	return false
@@ -897,6 +1908,8 @@ func historyStateSuffix(state HistoryState, spinnerIcon string) string {
 		return " ✓"
 	case StateFailed:
 		return " ✗"
+	case StateCancelled:
+		return " ⊘"
 	default:
 		return ""
 	}